@@ -1,21 +1,34 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"go-flix-api/config"
 	_ "go-flix-api/docs" // Import generated docs
+	"go-flix-api/internal/apikey"
 	"go-flix-api/internal/auth"
+	"go-flix-api/internal/database"
+	"go-flix-api/internal/health"
 	"go-flix-api/internal/middleware"
 	"go-flix-api/internal/movie"
+	"go-flix-api/internal/problem"
+	"go-flix-api/internal/ratelimit"
+	"go-flix-api/internal/rbac"
+	"go-flix-api/internal/signing"
+	"go-flix-api/internal/user"
 
 	"github.com/gorilla/mux"
-	"github.com/jmoiron/sqlx"
 	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
@@ -38,6 +51,11 @@ import (
 // @name Authorization
 // @description Type "Bearer" followed by a space and JWT token.
 
+// @securityDefinitions.apikey APIKeyAuth
+// @in header
+// @name X-API-Key
+// @description API key for machine-to-machine clients (gf_<prefix>_<secret>).
+
 // ... (Komentar Swagger Anda) ...
 func main() {
 	// Setup logger, .env, config, dan database (tetap sama)
@@ -46,65 +64,320 @@ func main() {
 	if err := godotenv.Load(); err != nil {
 		slog.Warn("Peringatan: Gagal memuat file .env")
 	}
-	cfg, err := config.LoadConfig("config.yml")
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "migrate:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	flags := flag.NewFlagSet("go-flix-api", flag.ExitOnError)
+	configPath := configFlag(flags)
+	flags.Parse(os.Args[1:])
+	configFile := configPath()
+	cfg, err := config.Load(configFile)
 	if err != nil {
-		slog.Error("Fatal: Gagal memuat config.yml", "error", err)
+		slog.Error("Fatal: Konfigurasi tidak valid", "error", err)
 		os.Exit(1)
 	}
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", cfg.Database.Host, cfg.Database.Port, cfg.Database.User, cfg.Database.Password, cfg.Database.DBName)
-	db, err := sqlx.Connect("postgres", dsn)
+	// DSN hanya masuk log dalam bentuk yang sudah disensor
+	db, err := database.Connect(context.Background(), cfg.Database)
 	if err != nil {
 		slog.Error("Fatal: Gagal koneksi ke database", "error", err)
 		os.Exit(1)
 	}
-	defer db.Close()
-	slog.Info("Koneksi database berhasil")
-	slog.Info("Mencoba koneksi dengan DSN", "dsn", dsn)
+	dsn, _ := database.DSN(cfg.Database)
+	slog.Info("Koneksi database berhasil", "dsn", database.Redact(dsn), "max_open_conns", cfg.Database.MaxOpenConns)
+
+	// Migrasi otomatis: aman untuk banyak replika karena dijaga advisory lock
+	if cfg.Database.AutoMigrate {
+		applied, err := migrator(db).Up(context.Background())
+		for _, m := range applied {
+			slog.Info("Migrasi dijalankan", "version", m.Version, "name", m.Name)
+		}
+		if err != nil {
+			slog.Error("Fatal: Migrasi database gagal", "error", err)
+			os.Exit(1)
+		}
+	}
 
 	// === PERBAIKAN UTAMA DI SINI (Dependency Injection yang Benar) ===
 
 	// 1. Inisialisasi semua service
-	authService := auth.NewService(cfg)
+	// Denylist di PostgreSQL agar logout bertahan saat restart dan berlaku di semua replika
+	var denylist auth.Denylist
+	var refreshStore auth.RefreshStore
+	switch cfg.JWT.Denylist.Backend {
+	case "memory":
+		denylist = auth.NewMemoryDenylist()
+		refreshStore = auth.NewMemoryRefreshStore()
+	case "", "postgres":
+		denylist = auth.NewPostgresDenylist(db)
+		refreshStore = auth.NewPostgresRefreshStore(db)
+	default:
+		slog.Error("Fatal: Backend denylist tidak dikenal", "backend", cfg.JWT.Denylist.Backend)
+		os.Exit(1)
+	}
+
+	// Hitungan login gagal punya backend sendiri: di memori setiap replika menghitung
+	// terpisah, sehingga tebakan yang disebar ke banyak replika tidak terkunci
+	var attemptStore auth.AttemptStore
+	switch cfg.LoginThrottle.Backend {
+	case "memory":
+		attemptStore = auth.NewMemoryAttemptStore()
+	case "", "postgres":
+		attemptStore = auth.NewPostgresAttemptStore(db)
+	default:
+		slog.Error("Fatal: Backend login_throttle tidak dikenal", "backend", cfg.LoginThrottle.Backend)
+		os.Exit(1)
+	}
+
+	// Rate limit per group route; bucket di memori kecuali rate_limit.backend = postgres
+	rateRules := map[string]ratelimit.Rule{}
+	var rateIdle time.Duration
+	for group, rule := range cfg.RateLimit.Groups {
+		if group != "auth" && group != "api" {
+			slog.Warn("Group rate limit tidak dikenal, diabaikan", "group", group)
+		}
+		rateRules[group] = ratelimit.Rule{Limit: rule.Limit, Period: rule.Period}
+		rateIdle = max(rateIdle, rule.Period)
+	}
+	var limiter ratelimit.Limiter
+	switch cfg.RateLimit.Backend {
+	case "", "memory":
+		limiter = ratelimit.NewMemoryLimiter(rateIdle)
+	case "postgres":
+		limiter = ratelimit.NewPostgresLimiter(db, rateIdle)
+	default:
+		slog.Error("Fatal: Backend rate limit tidak dikenal", "backend", cfg.RateLimit.Backend)
+		os.Exit(1)
+	}
+	rateLimit := func(group string) func(http.Handler) http.Handler {
+		rule, ok := rateRules[group]
+		if !ok {
+			return func(next http.Handler) http.Handler { return next }
+		}
+		return middleware.RateLimit(limiter, group, rule)
+	}
+	rateLimitIP := func(group string) func(http.Handler) http.Handler {
+		rule, ok := rateRules[group]
+		if !ok {
+			return func(next http.Handler) http.Handler { return next }
+		}
+		return middleware.RateLimitClientIP(limiter, group, rule)
+	}
+
+	janitorCtx, stopJanitor := context.WithCancel(context.Background())
+	auth.StartJanitor(janitorCtx, cfg.JWT.Denylist.SweepInterval, denylist, refreshStore, attemptStore, limiter)
+
+	// Akun disimpan di tabel users; isi dari config.yml hanya saat tabel masih kosong
+	// Ubah password/role atau hapus akun ikut mencabut sesi dan API key milik user itu
+	apiKeyService := apikey.NewService(apikey.NewRepository(db))
+	userService := user.NewService(user.NewRepository(db), auth.NewSessionRevoker(denylist, refreshStore), apiKeyService)
+	imported, err := userService.ImportUsers(context.Background(), cfg.Users)
+	if err != nil {
+		slog.Error("Fatal: Gagal mengimpor user dari config", "error", err)
+		os.Exit(1)
+	}
+	if imported > 0 {
+		slog.Info("User dari config diimpor ke database", "count", imported)
+	}
+
+	// Kunci JWT: RS256/EdDSA dari file PEM (jwt.keys), atau HS256 dari jwt.secret
+	keySet, err := signing.Load(cfg.JWT)
+	if err != nil {
+		slog.Error("Fatal: Gagal memuat kunci JWT", "error", err)
+		os.Exit(1)
+	}
+	keys := signing.NewKeyring(keySet)
+
+	throttle := auth.NewLoginThrottle(attemptStore, cfg.LoginThrottle)
+	authService := auth.NewService(cfg, keys, userService, denylist, refreshStore, throttle)
+
+	// Reload saat SIGHUP atau file config berubah: kunci JWT, TTL token, dan user baru dari config.
+	// Kunci lama tetap diterima selama jwt.reload_grace agar token yang sudah terbit tidak langsung mati.
+	live := cfg
+	watcher := config.NewWatcher(configFile, cfg, func(next *config.Config) error {
+		nextKeys, err := signing.Load(next.JWT)
+		if err != nil {
+			return fmt.Errorf("kunci JWT: %w", err)
+		}
+		created, err := userService.ImportNewUsers(context.Background(), next.Users)
+		if err != nil {
+			return fmt.Errorf("users: %w", err)
+		}
+		if created > 0 {
+			slog.Info("User baru dari config dibuat", "count", created)
+		}
+		grace := live.JWT.ReloadGrace
+		if grace == 0 {
+			grace = live.JWT.AccessTTL
+		}
+		keys.Rotate(nextKeys, grace)
+		authService.SetConfig(next)
+		live = next
+		return nil
+	})
+	go watcher.Run(janitorCtx, configPollInterval)
 	movieRepo := movie.NewRepository(db)
-	movieService := movie.NewService(movieRepo)
+	// Cursor pagination ditandatangani dengan secret tersendiri, fallback ke JWT secret
+	cursorSecret := cfg.Pagination.CursorSecret
+	if cursorSecret == "" {
+		cursorSecret = cfg.JWT.Secret
+	}
+	movieService := movie.NewService(movieRepo, movie.NewCursorCodec([]byte(cursorSecret), cfg.Pagination.CursorTTL))
 
 	// 2. Inisialisasi semua handler, berikan service yang dibutuhkan
 	authHandler := auth.NewHandler(authService)
+	apiKeyHandler := apikey.NewHandler(apiKeyService)
+	userHandler := user.NewHandler(userService, throttle)
 	movieHandler := movie.NewHandler(movieService)
 
 	// Router
 	r := mux.NewRouter()
+	// 404/405 bawaan mux berupa text/plain, samakan dengan format error JSON lainnya
+	r.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		problem.Error(w, req, http.StatusNotFound, problem.CodeNotFound, "Route not found")
+	})
+	r.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		problem.Error(w, req, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "Method not allowed")
+	})
 
 	// 3. Daftarkan rute dengan handler yang sudah diinisialisasi
-	r.HandleFunc("/api/login", authHandler.Login).Methods("POST", "OPTIONS")
+	r.HandleFunc("/.well-known/jwks.json", authHandler.JWKS).Methods("GET")
+	// Endpoint tanpa token dibatasi per IP client dengan kuota group "auth"
+	limitAuth := rateLimit("auth")
+	r.Handle("/api/login", limitAuth(http.HandlerFunc(authHandler.Login))).Methods("POST", "OPTIONS")
+	r.Handle("/api/token/refresh", limitAuth(http.HandlerFunc(authHandler.Refresh))).Methods("POST", "OPTIONS")
+	r.Handle("/api/register", limitAuth(http.HandlerFunc(userHandler.Register))).Methods("POST", "OPTIONS")
 	r.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
-	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) { /* ... */ }).Methods("GET")
+	// /health = proses hidup, /ready = siap menerima trafik (gagal saat shutdown atau database mati)
+	checker := health.NewChecker(db)
+	r.HandleFunc("/health", checker.Live).Methods("GET")
+	r.HandleFunc("/ready", checker.Ready).Methods("GET")
 
 	// Subrouter untuk Rute Terproteksi
 	api := r.PathPrefix("/api").Subrouter()
 	// 4. Berikan semua argumen yang dibutuhkan oleh middleware
-	api.Use(middleware.AuthMiddleware(cfg.JWT.Secret, authService.IsTokenRevoked))
+	// Kredensial opsional: tanpa kredensial request dianggap anonim, dan setiap rute di bawah
+	// menentukan sendiri apakah anonim boleh masuk (RequireAuth/RequirePermission)
+	// userService menolak access token yang terbit sebelum password/role user berubah
+	strategies := []middleware.Strategy{middleware.BearerJWT(keys, denylist, userService), apikey.NewStrategy(apiKeyService)}
+	if cfg.JWT.SessionCookie != "" {
+		strategies = append(strategies, middleware.SessionCookie(cfg.JWT.SessionCookie, keys, denylist, userService))
+	}
+	// Kuota per IP sebelum autentikasi: kredensial salah tetap terhitung, jadi API key
+	// atau token tidak bisa ditebak tanpa batas
+	api.Use(rateLimitIP("api"))
+	api.Use(middleware.NewAuthenticator(strategies...).Middleware)
+	// Setelah autentikasi, agar kuota juga dihitung per user/API key
+	api.Use(rateLimit("api"))
+
+	// Cukup login, tanpa permission tambahan
+	api.Handle("/logout", middleware.RequireAuth(http.HandlerFunc(authHandler.Logout))).Methods("POST", "OPTIONS")
+	api.Handle("/me/password", middleware.RequireAuth(http.HandlerFunc(userHandler.ChangePassword))).Methods("PUT", "OPTIONS")
+
+	// Kebijakan per rute: anonim dan viewer boleh membaca katalog, editor boleh menulis,
+	// admin boleh trash/restore/hard delete dan mengelola user (lihat internal/rbac).
+	// Request anonim yang ditolak mendapat 401, user login tanpa permission mendapat 403.
+	can := func(p rbac.Permission, h http.HandlerFunc) http.Handler {
+		return middleware.RequirePermission(p)(h)
+	}
+	api.Handle("/movies", can(rbac.MoviesRead, movieHandler.GetAllMovies)).Methods("GET")
+	api.Handle("/movies", can(rbac.MoviesWrite, movieHandler.CreateMovie)).Methods("POST", "OPTIONS")
+	api.Handle("/movies/search", can(rbac.MoviesRead, movieHandler.SearchMovies)).Methods("GET")
+
+	// Didaftarkan sebelum /movies/{id} agar "trash" dan ?hard=true tidak tertangkap rute biasa.
+	api.Handle("/movies/trash", can(rbac.MoviesPurge, movieHandler.ListTrash)).Methods("GET")
+	api.Handle("/movies/{id}/restore", can(rbac.MoviesPurge, movieHandler.RestoreMovie)).Methods("POST", "OPTIONS")
+	api.Handle("/movies/{id}", can(rbac.MoviesPurge, movieHandler.PurgeMovie)).Methods("DELETE", "OPTIONS").Queries("hard", "true")
 
-	api.HandleFunc("/logout", authHandler.Logout).Methods("POST", "OPTIONS")
-	api.HandleFunc("/movies", movieHandler.GetAllMovies).Methods("GET")
-	api.HandleFunc("/movies", movieHandler.CreateMovie).Methods("POST", "OPTIONS")
-	api.HandleFunc("/movies/{id}", movieHandler.GetMovieByID).Methods("GET")
-	api.HandleFunc("/movies/{id}", movieHandler.UpdateMovie).Methods("PUT", "OPTIONS")
-	api.HandleFunc("/movies/{id}", movieHandler.DeleteMovie).Methods("DELETE", "OPTIONS")
+	api.Handle("/users", can(rbac.UsersManage, userHandler.ListUsers)).Methods("GET")
+	api.Handle("/users", can(rbac.UsersManage, userHandler.CreateUser)).Methods("POST", "OPTIONS")
+	api.Handle("/users/{id}", can(rbac.UsersManage, userHandler.GetUser)).Methods("GET")
+	api.Handle("/users/{id}", can(rbac.UsersManage, userHandler.UpdateUser)).Methods("PUT", "OPTIONS")
+	api.Handle("/users/{id}", can(rbac.UsersManage, userHandler.DeleteUser)).Methods("DELETE", "OPTIONS")
+
+	api.Handle("/api-keys", can(rbac.APIKeysManage, apiKeyHandler.ListAPIKeys)).Methods("GET")
+	api.Handle("/api-keys", can(rbac.APIKeysManage, apiKeyHandler.CreateAPIKey)).Methods("POST", "OPTIONS")
+	api.Handle("/api-keys/{id}", can(rbac.APIKeysManage, apiKeyHandler.RevokeAPIKey)).Methods("DELETE", "OPTIONS")
+
+	api.Handle("/movies/{id}", can(rbac.MoviesRead, movieHandler.GetMovieByID)).Methods("GET")
+	api.Handle("/movies/{id}", can(rbac.MoviesWrite, movieHandler.UpdateMovie)).Methods("PUT", "OPTIONS")
+	api.Handle("/movies/{id}", can(rbac.MoviesWrite, movieHandler.DeleteMovie)).Methods("DELETE", "OPTIONS")
 
 	// CORS Middleware dan Start Server (tetap sama)
-	finalHandler := corsMiddleware(r)
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
-	addr := ":" + port
-	slog.Info("🚀 Server siap berjalan", "address", fmt.Sprintf("http://localhost:%s", port))
-	if err := http.ListenAndServe(addr, finalHandler); err != nil {
+	// IP client asli dari X-Forwarded-For, hanya bila koneksi datang dari server.trusted_proxies
+	clientIPs, err := middleware.NewClientIPResolver(cfg.Server.TrustedProxies)
+	if err != nil {
+		slog.Error("Fatal: server.trusted_proxies tidak valid", "error", err)
+		os.Exit(1)
+	}
+	finalHandler := corsMiddleware(middleware.RequestID(clientIPs.Middleware(r)))
+	srv := &http.Server{
+		Addr:              ":" + cfg.Server.Port,
+		Handler:           finalHandler,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
+	}
+	stop, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.ListenAndServe() }()
+	slog.Info("🚀 Server siap berjalan", "address", fmt.Sprintf("http://localhost:%s", cfg.Server.Port))
+
+	select {
+	case err := <-serveErr:
 		slog.Error("Gagal menjalankan server", "error", err)
 		os.Exit(1)
+	case <-stop.Done():
+		stopSignals() // sinyal kedua langsung menghentikan proses
+	}
+
+	// Shutdown: /ready gagal dulu agar load balancer berhenti mengirim trafik,
+	// lalu tolak koneksi baru dan tunggu request yang sedang berjalan, terakhir tutup database
+	slog.Info("Sinyal shutdown diterima, menyelesaikan request yang berjalan", "timeout", cfg.Server.ShutdownTimeout)
+	checker.SetDraining()
+	time.Sleep(cfg.Server.ShutdownDelay)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Error("Request belum selesai saat batas waktu shutdown habis", "error", err)
+		srv.Close()
+	}
+	stopJanitor()
+	if err := db.Close(); err != nil {
+		slog.Error("Gagal menutup koneksi database", "error", err)
+	}
+	slog.Info("Server berhenti")
+}
+
+// configPollInterval adalah jeda pengecekan perubahan file config
+const configPollInterval = 5 * time.Second
+
+// configFlag mendaftarkan --config di flags; default GOFLIX_CONFIG atau config.yml.
+// Fungsi yang dikembalikan dipanggil setelah flags.Parse. Bila flag tidak diisi dan file
+// default tidak ada, hasilnya "" sehingga config hanya dari default + environment.
+func configFlag(flags *flag.FlagSet) func() string {
+	def := os.Getenv(config.EnvPrefix + "_CONFIG")
+	if def == "" {
+		def = "config.yml"
+	}
+	path := flags.String("config", def, "path ke file konfigurasi YAML")
+	return func() string {
+		explicit := os.Getenv(config.EnvPrefix+"_CONFIG") != ""
+		flags.Visit(func(f *flag.Flag) { explicit = explicit || f.Name == "config" })
+		if !explicit {
+			if _, err := os.Stat(*path); errors.Is(err, fs.ErrNotExist) {
+				return ""
+			}
+		}
+		return *path
 	}
-	slog.Info("Mencoba koneksi dengan DSN", "dsn", dsn)
 }
 
 // corsMiddleware (tetap sama)
@@ -112,7 +385,8 @@ func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key, If-Match, X-Request-ID")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, X-Request-ID, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, Retry-After")
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
 			return
@@ -0,0 +1,202 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const strongSecret = "Zx8-qL3vN9wR2mT7kP4sY6bH1cJ5dF0g"
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadLayersDefaultsFileAndEnv(t *testing.T) {
+	path := writeFile(t, "config.yml", `
+server:
+  port: "9090"
+database:
+  user: "postgres"
+  dbname: "go_flix_db"
+jwt:
+  secret: "`+strongSecret+`"
+  access_ttl: "30m"
+`)
+	t.Setenv("GOFLIX_JWT_ACCESS_TTL", "15m")
+	t.Setenv("GOFLIX_DATABASE_HOST", "db.internal")
+	t.Setenv("GOFLIX_SERVER_TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.1")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Server.Port != "9090" {
+		t.Errorf("file should override default port, got %q", cfg.Server.Port)
+	}
+	if cfg.JWT.AccessTTL != 15*time.Minute {
+		t.Errorf("env should override file access_ttl, got %v", cfg.JWT.AccessTTL)
+	}
+	if cfg.Database.Host != "db.internal" || cfg.Database.Port != "5432" {
+		t.Errorf("unexpected database host/port %q:%q", cfg.Database.Host, cfg.Database.Port)
+	}
+	if cfg.JWT.RefreshTTL != 720*time.Hour || cfg.Pagination.CursorTTL != 24*time.Hour {
+		t.Errorf("defaults not applied: refresh=%v cursor=%v", cfg.JWT.RefreshTTL, cfg.Pagination.CursorTTL)
+	}
+	if got := strings.Join(cfg.Server.TrustedProxies, "|"); got != "10.0.0.0/8|192.168.1.1" {
+		t.Errorf("unexpected trusted proxies %q", got)
+	}
+}
+
+func TestLoadWithoutFile(t *testing.T) {
+	t.Setenv("GOFLIX_DATABASE_USER", "postgres")
+	t.Setenv("GOFLIX_DATABASE_DBNAME", "go_flix_db")
+	t.Setenv("GOFLIX_JWT_SECRET", strongSecret)
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Server.Port != "8080" {
+		t.Errorf("expected default port, got %q", cfg.Server.Port)
+	}
+}
+
+func TestLoadSecretFromFile(t *testing.T) {
+	t.Setenv("GOFLIX_DATABASE_USER", "postgres")
+	t.Setenv("GOFLIX_DATABASE_DBNAME", "go_flix_db")
+	t.Setenv("GOFLIX_JWT_SECRET_FILE", writeFile(t, "jwt_secret", strongSecret+"\n"))
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.JWT.Secret != strongSecret {
+		t.Errorf("expected secret from file without trailing newline, got %q", cfg.JWT.Secret)
+	}
+
+	t.Setenv("GOFLIX_JWT_SECRET", strongSecret)
+	if _, err := Load(""); err == nil || !strings.Contains(err.Error(), "GOFLIX_JWT_SECRET_FILE") {
+		t.Fatalf("expected error when both variable and _FILE are set, got %v", err)
+	}
+}
+
+func TestLoadRejectsUnknownKey(t *testing.T) {
+	path := writeFile(t, "config.yml", "jwt:\n  secrt: \"typo\"\n")
+	if _, err := Load(path); err == nil {
+		t.Fatal("expected unknown key to be rejected")
+	}
+}
+
+func TestValidateRejectsWeakSecrets(t *testing.T) {
+	for _, secret := range []string{
+		"",
+		"short-but-random-9f2K",
+		"kunci_rahasia_yang_sangat_aman_dan_panjang_sekali",
+		strings.Repeat("ab", 20),
+	} {
+		cfg := Defaults()
+		cfg.Database.User, cfg.Database.DBName = "postgres", "go_flix_db"
+		cfg.JWT.Secret = secret
+		err := cfg.Validate()
+		if err == nil || !strings.Contains(err.Error(), "jwt.secret") {
+			t.Errorf("secret %q: expected jwt.secret error, got %v", secret, err)
+		}
+	}
+}
+
+func TestValidateSecretMessages(t *testing.T) {
+	cfg := Defaults()
+	cfg.Database.User, cfg.Database.DBName = "postgres", "go_flix_db"
+	cfg.JWT.Secret = "changeme"
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "jwt.secret is a published placeholder") {
+		t.Fatalf("expected short placeholder reported as placeholder, got %v", err)
+	}
+
+	if err := checkSecret("pagination.cursor_secret", ""); err == nil ||
+		!strings.Contains(err.Error(), "GOFLIX_PAGINATION_CURSOR_SECRET_FILE") {
+		t.Fatalf("expected the cursor secret env variable in the error, got %v", err)
+	}
+}
+
+func TestValidateReportsEveryError(t *testing.T) {
+	cfg := Defaults()
+	cfg.Server.Port = "http"
+	cfg.Database.User = "postgres"
+	cfg.JWT.Secret = "secret"
+	cfg.JWT.RefreshTTL = time.Minute
+	cfg.RateLimit.Groups = map[string]RateLimitRule{"api": {Limit: 0, Period: time.Minute}}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, want := range []string{"server.port", "database.dbname", "jwt.secret", "jwt.refresh_ttl", "rate_limit.groups.api"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in error, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestWatcherReload(t *testing.T) {
+	base := `
+database:
+  user: "postgres"
+  dbname: "go_flix_db"
+jwt:
+  secret: "` + strongSecret + `"
+  access_ttl: "%s"
+`
+	path := writeFile(t, "config.yml", strings.Replace(base, "%s", "1h", 1))
+	current, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	var applied []*Config
+	w := NewWatcher(path, current, func(c *Config) error {
+		applied = append(applied, c)
+		return nil
+	})
+
+	rewrite := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rewrite(strings.Replace(base, "%s", "15m", 1))
+	if err := w.Reload(); err != nil {
+		t.Fatalf("valid reload rejected: %v", err)
+	}
+	if len(applied) != 1 || applied[0].JWT.AccessTTL != 15*time.Minute {
+		t.Fatalf("expected new config applied, got %d applies", len(applied))
+	}
+	// Reload lewat SIGHUP juga mencatat isi file, jadi tick berikutnya tidak memuat ulang lagi
+	if data, _ := os.ReadFile(path); !bytes.Equal(w.lastFile, data) {
+		t.Fatalf("successful reload must record the file contents")
+	}
+
+	// Secret lemah: ditolak sebelum apply dipanggil
+	rewrite(strings.Replace(strings.Replace(base, "%s", "15m", 1), strongSecret, "secret", 1))
+	if err := w.Reload(); err == nil || !strings.Contains(err.Error(), "jwt.secret") {
+		t.Fatalf("expected weak secret to be rejected, got %v", err)
+	}
+
+	// Port hanya dibaca saat start
+	rewrite(strings.Replace(base, "%s", "15m", 1) + "server:\n  port: \"9090\"\n")
+	if err := w.Reload(); err == nil || !strings.Contains(err.Error(), "server") {
+		t.Fatalf("expected restart-only change to be rejected, got %v", err)
+	}
+	if len(applied) != 1 {
+		t.Fatalf("rejected reloads must not be applied, got %d applies", len(applied))
+	}
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvPrefix is the prefix of every environment variable that overrides the config file.
+// Nama variabel mengikuti path yaml: jwt.access_ttl -> GOFLIX_JWT_ACCESS_TTL.
+const EnvPrefix = "GOFLIX"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// applyEnv overrides cfg with GOFLIX_* variables. Every string, number, bool, duration
+// and string list field can be set; lists are comma-separated. Setting NAME_FILE instead of
+// NAME reads the value from that file, for secrets mounted by Docker or Kubernetes.
+// Lists of structs (jwt.keys, users) and maps (rate_limit.groups) are file-only.
+func applyEnv(cfg *Config, lookup func(string) (string, bool)) error {
+	return errors.Join(applyEnvValue(reflect.ValueOf(cfg).Elem(), EnvPrefix, lookup)...)
+}
+
+func applyEnvValue(v reflect.Value, prefix string, lookup func(string) (string, bool)) []error {
+	var errs []error
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if tag == "" || tag == "-" {
+			continue
+		}
+		name := prefix + "_" + strings.ToUpper(tag)
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			errs = append(errs, applyEnvValue(field, name, lookup)...)
+			continue
+		}
+		if !settable(field.Type()) {
+			continue
+		}
+		raw, ok, err := lookupEnv(name, lookup)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if err := setField(field, raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errs
+}
+
+// lookupEnv returns the value of name, or the content of the file named by name_FILE
+func lookupEnv(name string, lookup func(string) (string, bool)) (string, bool, error) {
+	value, ok := lookup(name)
+	path, fromFile := lookup(name + "_FILE")
+	if !fromFile {
+		return value, ok, nil
+	}
+	if ok {
+		return "", false, fmt.Errorf("%s and %s_FILE are both set, use only one", name, name)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("%s_FILE: %w", name, err)
+	}
+	// File secret biasanya diakhiri newline
+	return strings.TrimRight(string(data), "\r\n"), true, nil
+}
+
+func settable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int64:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.String
+	}
+	return false
+}
+
+func setField(field reflect.Value, raw string) error {
+	switch {
+	case field.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+	case field.Kind() == reflect.String:
+		field.SetString(raw)
+	case field.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case field.Kind() == reflect.Int, field.Kind() == reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case field.Kind() == reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	}
+	return nil
+}
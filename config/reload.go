@@ -0,0 +1,122 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// RestartRequired lists the sections of next that differ from old but are only read at startup.
+// Hanya jwt (kecuali denylist dan session_cookie) dan users yang bisa diganti tanpa restart.
+func RestartRequired(old, next *Config) []string {
+	var changed []string
+	check := func(name string, a, b any) {
+		if !reflect.DeepEqual(a, b) {
+			changed = append(changed, name)
+		}
+	}
+	check("server", old.Server, next.Server)
+	check("database", old.Database, next.Database)
+	check("jwt.denylist", old.JWT.Denylist, next.JWT.Denylist)
+	check("jwt.session_cookie", old.JWT.SessionCookie, next.JWT.SessionCookie)
+	check("login_throttle", old.LoginThrottle, next.LoginThrottle)
+	check("rate_limit", old.RateLimit, next.RateLimit)
+	check("pagination", old.Pagination, next.Pagination)
+	return changed
+}
+
+// Watcher reloads the configuration on SIGHUP and whenever the file at path changes.
+// A reload that fails to load, fails validation, touches a startup-only section or is
+// rejected by apply is logged and dropped; the running configuration stays as it was.
+type Watcher struct {
+	path     string
+	current  *Config
+	apply    func(*Config) error
+	lastFile []byte
+}
+
+// NewWatcher watches path, starting from the already-applied current config.
+// apply must swap the new config in completely or return an error and change nothing.
+func NewWatcher(path string, current *Config, apply func(*Config) error) *Watcher {
+	w := &Watcher{path: path, current: current, apply: apply}
+	w.lastFile, _ = w.readFile()
+	return w
+}
+
+// Run handles SIGHUP and polls the file every interval until ctx is done.
+// Tanpa file (path kosong) hanya SIGHUP yang dipakai, misalnya setelah secret di *_FILE diganti.
+func (w *Watcher) Run(ctx context.Context, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	if w.path != "" && interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			w.reload("sighup")
+		case <-tick:
+			data, err := w.readFile()
+			// File yang sedang ditulis ulang atau hilang sesaat dibiarkan sampai tick berikutnya
+			if err != nil || bytes.Equal(data, w.lastFile) {
+				continue
+			}
+			// Dicatat juga saat reload ditolak, supaya file yang sama tidak dicoba ulang setiap tick
+			w.lastFile = data
+			w.reload("file_changed")
+		}
+	}
+}
+
+func (w *Watcher) reload(trigger string) {
+	if err := w.Reload(); err != nil {
+		slog.Error("Reload konfigurasi ditolak, konfigurasi lama tetap dipakai", "trigger", trigger, "error", err)
+		return
+	}
+	slog.Info("Konfigurasi dimuat ulang", "trigger", trigger, "path", w.path)
+}
+
+// Reload loads, validates and applies the configuration once.
+// On success the file contents are remembered, so a reload triggered by SIGHUP is not
+// repeated by the next poll (which would rotate the JWT keys a second time).
+func (w *Watcher) Reload() error {
+	// Dibaca sebelum Load: kalau file berubah di antaranya, tick berikutnya tetap memuat ulang
+	data, err := w.readFile()
+	if err != nil {
+		return err
+	}
+	next, err := Load(w.path)
+	if err != nil {
+		return err
+	}
+	if changed := RestartRequired(w.current, next); len(changed) > 0 {
+		return fmt.Errorf("changes to %s need a restart", strings.Join(changed, ", "))
+	}
+	if err := w.apply(next); err != nil {
+		return err
+	}
+	w.current = next
+	w.lastFile = data
+	return nil
+}
+
+func (w *Watcher) readFile() ([]byte, error) {
+	if w.path == "" {
+		return nil, nil
+	}
+	return os.ReadFile(w.path)
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// MinSecretLength is the shortest accepted jwt.secret and pagination.cursor_secret (bytes)
+const MinSecretLength = 32
+
+// Placeholder dari README, contoh config dan versi lama repo ini; jangan pernah dipakai sungguhan
+var knownWeakSecrets = []string{
+	"secret",
+	"changeme",
+	"your_jwt_secret_key",
+	"your-super-secret-jwt-key-change-this-in-production",
+	"kunci_rahasia_yang_sangat_aman",
+	"kunci_rahasia_yang_sangat_aman_dan_panjang_sekali",
+}
+
+// Validate checks the whole configuration and returns every problem found, joined
+func (c *Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if port, err := strconv.Atoi(c.Server.Port); err != nil || port < 1 || port > 65535 {
+		add("server.port: %q is not a port number", c.Server.Port)
+	}
+	srv := c.Server
+	if srv.ReadHeaderTimeout < 0 || srv.ReadTimeout < 0 || srv.WriteTimeout < 0 || srv.IdleTimeout < 0 {
+		add("server: timeouts must not be negative")
+	}
+	if srv.MaxHeaderBytes < 0 {
+		add("server.max_header_bytes must not be negative")
+	}
+	if srv.ShutdownDelay < 0 || srv.ShutdownTimeout <= 0 {
+		add("server: shutdown_delay must not be negative and shutdown_timeout must be positive")
+	}
+
+	errs = append(errs, c.Database.validate()...)
+
+	if len(c.JWT.Keys) == 0 {
+		if err := checkSecret("jwt.secret", c.JWT.Secret); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.JWT.AccessTTL <= 0 {
+		add("jwt.access_ttl must be positive")
+	}
+	if c.JWT.RefreshTTL <= c.JWT.AccessTTL {
+		add("jwt.refresh_ttl must be longer than jwt.access_ttl")
+	}
+	if c.JWT.ReloadGrace < 0 {
+		add("jwt.reload_grace must not be negative")
+	}
+	if c.JWT.Leeway < 0 {
+		add("jwt.leeway must not be negative")
+	}
+	if b := c.JWT.Denylist.Backend; b != "" && b != "postgres" && b != "memory" {
+		add("jwt.denylist.backend: %q must be postgres or memory", b)
+	}
+	if c.JWT.Denylist.SweepInterval <= 0 {
+		add("jwt.denylist.sweep_interval must be positive")
+	}
+
+	lt := c.LoginThrottle
+	if b := lt.Backend; b != "" && b != "postgres" && b != "memory" {
+		add("login_throttle.backend: %q must be postgres or memory", b)
+	}
+	if lt.FreeAttempts < 0 || lt.LockoutThreshold < 0 || lt.IPLockoutThreshold < 0 {
+		add("login_throttle: attempt counts must not be negative")
+	}
+	if lt.BaseDelay < 0 || lt.MaxDelay < 0 || lt.LockoutDuration < 0 {
+		add("login_throttle: durations must not be negative")
+	}
+
+	if b := c.RateLimit.Backend; b != "" && b != "postgres" && b != "memory" {
+		add("rate_limit.backend: %q must be postgres or memory", b)
+	}
+	for group, rule := range c.RateLimit.Groups {
+		if rule.Limit <= 0 || rule.Period <= 0 {
+			add("rate_limit.groups.%s: limit and period must be positive", group)
+		}
+	}
+
+	// Cursor jatuh ke jwt.secret bila cursor_secret kosong; dengan jwt.keys tidak ada fallback
+	switch {
+	case c.Pagination.CursorSecret != "":
+		if err := checkSecret("pagination.cursor_secret", c.Pagination.CursorSecret); err != nil {
+			errs = append(errs, err)
+		}
+	case len(c.JWT.Keys) > 0:
+		add("pagination.cursor_secret is required when jwt.keys is used")
+	}
+	if c.Pagination.CursorTTL <= 0 {
+		add("pagination.cursor_ttl must be positive")
+	}
+
+	for i, u := range c.Users {
+		if u.Username == "" {
+			add("users[%d].username is required", i)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (d DatabaseConfig) validate() []error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+	if d.URL != "" {
+		if u, err := url.Parse(d.URL); err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") {
+			// url bisa berisi password, jadi jangan dikutip di pesan error
+			add("database.url must be a postgres:// URL")
+		}
+	} else {
+		if d.Host == "" {
+			add("database.host is required")
+		}
+		if d.User == "" {
+			add("database.user is required")
+		}
+		if d.DBName == "" {
+			add("database.dbname is required")
+		}
+	}
+	switch d.SSLMode {
+	case "", "disable", "require", "verify-ca", "verify-full":
+	default:
+		add("database.sslmode: %q must be disable, require, verify-ca or verify-full", d.SSLMode)
+	}
+	if (d.SSLCertFile == "") != (d.SSLKeyFile == "") {
+		add("database.ssl_cert_file and database.ssl_key_file must be set together")
+	}
+	if d.MaxOpenConns < 0 || d.MaxIdleConns < 0 {
+		add("database: max_open_conns and max_idle_conns must not be negative")
+	}
+	if d.MaxOpenConns > 0 && d.MaxIdleConns > d.MaxOpenConns {
+		add("database.max_idle_conns must not exceed max_open_conns")
+	}
+	if d.ConnMaxLifetime < 0 || d.StatementTimeout < 0 || d.ConnectTimeout < 0 {
+		add("database: conn_max_lifetime, statement_timeout and connect_timeout must not be negative")
+	}
+	return errs
+}
+
+// checkSecret rejects empty, short, placeholder and low-variety secrets
+func checkSecret(field, secret string) error {
+	if secret == "" {
+		// jwt.secret -> GOFLIX_JWT_SECRET_FILE, sama dengan penamaan di applyEnv
+		env := EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(field, ".", "_"))
+		return fmt.Errorf("%s is required (or set %s_FILE)", field, env)
+	}
+	// Sebelum cek panjang, agar placeholder pendek juga dilaporkan sebagai placeholder
+	for _, weak := range knownWeakSecrets {
+		if strings.EqualFold(secret, weak) {
+			return fmt.Errorf("%s is a published placeholder, generate a random one", field)
+		}
+	}
+	if len(secret) < MinSecretLength {
+		return fmt.Errorf("%s is too short: %d bytes, need at least %d", field, len(secret), MinSecretLength)
+	}
+	distinct := map[rune]bool{}
+	for _, r := range secret {
+		distinct[r] = true
+	}
+	if len(distinct) < 10 {
+		return fmt.Errorf("%s is too repetitive, generate a random one", field)
+	}
+	return nil
+}
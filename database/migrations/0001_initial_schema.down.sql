@@ -0,0 +1,9 @@
+-- Menghapus seluruh skema awal beserta datanya. Extension pg_trgm dibiarkan karena bisa dipakai database lain.
+DROP TABLE IF EXISTS rate_limit_buckets;
+DROP TABLE IF EXISTS login_attempts;
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS revoked_tokens;
+DROP TABLE IF EXISTS movies;
+DROP FUNCTION IF EXISTS movies_search_vector_update();
@@ -0,0 +1,135 @@
+-- Skema awal, sama dengan database/schema.sql sebelum ada migrasi.
+-- Semua statement idempotent sehingga database yang dulu dibuat manual bisa langsung
+-- dicatat di schema_migrations tanpa kehilangan data.
+
+-- Skrip untuk membuat tabel movies
+CREATE TABLE IF NOT EXISTS movies (
+    id UUID PRIMARY KEY,
+    judul VARCHAR(255) NOT NULL,
+    genre VARCHAR(100) NOT NULL,
+    tahun_rilis INT NOT NULL,
+    sutradara VARCHAR(100) NOT NULL,
+    pemeran TEXT[] NOT NULL,
+    
+    -- Kolom Audit Standar
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    created_by VARCHAR(100),
+    updated_by VARCHAR(100),
+    deleted_by VARCHAR(100),
+    version INT DEFAULT 1
+);
+
+-- Untuk database yang dibuat sebelum kolom deleted_by ada
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS deleted_by VARCHAR(100);
+
+-- Index untuk keyset (cursor) pagination: ORDER BY created_at DESC, id DESC
+-- dan seek WHERE (created_at, id) < ($1, $2), hanya untuk baris yang belum dihapus
+CREATE INDEX IF NOT EXISTS idx_movies_created_at_id
+    ON movies (created_at DESC, id DESC)
+    WHERE deleted_at IS NULL;
+
+-- Full-text search atas judul, sutradara dan pemeran.
+-- Memakai trigger (bukan generated column) karena array_to_string tidak IMMUTABLE.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS search_vector tsvector;
+
+CREATE OR REPLACE FUNCTION movies_search_vector_update() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector :=
+        setweight(to_tsvector('simple', coalesce(NEW.judul, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(NEW.sutradara, '')), 'B') ||
+        setweight(to_tsvector('simple', coalesce(array_to_string(NEW.pemeran, ' '), '')), 'C');
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS movies_search_vector_trigger ON movies;
+CREATE TRIGGER movies_search_vector_trigger
+    BEFORE INSERT OR UPDATE OF judul, sutradara, pemeran ON movies
+    FOR EACH ROW EXECUTE FUNCTION movies_search_vector_update();
+
+-- Isi search_vector untuk baris yang sudah ada sebelum trigger dibuat
+UPDATE movies SET judul = judul WHERE search_vector IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_movies_search_vector ON movies USING GIN (search_vector);
+
+-- Index trigram untuk fallback pencarian dengan salah ketik (mis. "Inteception")
+CREATE INDEX IF NOT EXISTS idx_movies_judul_trgm ON movies USING GIN (judul gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_movies_sutradara_trgm ON movies USING GIN (sutradara gin_trgm_ops);
+
+-- Denylist JWT yang sudah di-logout, dibagi oleh semua replika.
+-- Baris dengan expires_at yang sudah lewat dihapus berkala oleh janitor.
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti VARCHAR(64) PRIMARY KEY,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
+
+-- Refresh token (hanya hash SHA-256 yang disimpan). Semua token hasil rotasi
+-- dari satu login berbagi family_id; memakai ulang token lama mencabut seluruh family.
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id UUID PRIMARY KEY,
+    family_id UUID NOT NULL,
+    username VARCHAR(100) NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    rotated_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_expires_at ON refresh_tokens (expires_at);
+
+-- Akun user. Password disimpan sebagai hash bcrypt, username unik tanpa membedakan huruf besar/kecil.
+CREATE TABLE IF NOT EXISTS users (
+    id UUID PRIMARY KEY,
+    username VARCHAR(50) NOT NULL,
+    password_hash VARCHAR(255) NOT NULL,
+    role VARCHAR(20) NOT NULL DEFAULT 'viewer' CHECK (role IN ('viewer', 'editor', 'admin')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_lower ON users (LOWER(username));
+
+-- Role lama "user" (sebelum RBAC) boleh menulis film, setara dengan editor
+UPDATE users SET role = 'editor' WHERE role = 'user';
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'viewer';
+
+-- API key untuk klien mesin-ke-mesin. Hanya hash SHA-256 yang disimpan;
+-- prefix (bagian gf_<prefix>_ dari key) dipakai untuk lookup dan tampil di daftar.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    prefix CHAR(8) NOT NULL UNIQUE,
+    key_hash CHAR(64) NOT NULL,
+    scopes TEXT[] NOT NULL,
+    created_by VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Hitungan login gagal per username ("user:<username>") dan per IP ("ip:<alamat>"),
+-- dibagi oleh semua replika. Baris kedaluwarsa dihapus oleh janitor.
+CREATE TABLE IF NOT EXISTS login_attempts (
+    key VARCHAR(150) PRIMARY KEY,
+    failures INTEGER NOT NULL,
+    last_failure_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_expires_at ON login_attempts (expires_at);
+
+-- Token bucket rate limit bersama (rate_limit.backend: postgres). Key berbentuk
+-- "<group>:user:<username>", "<group>:apikey:<prefix>" atau "<group>:ip:<alamat>".
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+    key VARCHAR(200) PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    allowed BOOLEAN NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_updated_at ON rate_limit_buckets (updated_at);
@@ -0,0 +1,2 @@
+-- Semua sesi satu user dicabut sekaligus saat password, role atau akunnya berubah.
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_username_lower ON refresh_tokens (LOWER(username));
@@ -0,0 +1,3 @@
+-- Access token dengan iat sebelum kolom ini ditolak. Diisi saat akun dibuat dan setiap
+-- kali password atau role berubah, agar token lama tidak membawa role lama sampai kedaluwarsa.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tokens_valid_after TIMESTAMP WITH TIME ZONE;
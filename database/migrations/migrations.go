@@ -0,0 +1,10 @@
+// Package migrations embeds the numbered SQL migrations into the binary.
+// Buat file baru dengan: go-flix-api migrate create <nama>
+package migrations
+
+import "embed"
+
+// FS holds every NNNN_name.up.sql and NNNN_name.down.sql in this directory
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,26 @@
+package apikey
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// Domain errors returned by Service. Handlers map them to status codes with errors.Is.
+var (
+	// ErrNotFound means no API key has the requested ID
+	ErrNotFound = errors.New("api key not found")
+	// ErrValidation means the request is invalid.
+	// Request validation failures also wrap validation.Errors with the per-field details.
+	ErrValidation = errors.New("invalid api key request")
+	// ErrInvalidKey means the presented key is malformed, unknown, expired or revoked.
+	// Alasannya tidak dibedakan untuk client.
+	ErrInvalidKey = errors.New("invalid api key")
+)
+
+// translateError maps sql.ErrNoRows onto ErrNotFound
+func translateError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,103 @@
+package apikey
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"go-flix-api/internal/middleware"
+	"go-flix-api/internal/problem"
+	"go-flix-api/internal/validation"
+	"go-flix-api/models"
+
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// writeError maps service errors onto problem responses with the matching status code
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var verrs validation.Errors
+	switch {
+	case errors.As(err, &verrs):
+		problem.Validation(w, r, verrs)
+	case errors.Is(err, ErrValidation):
+		problem.Error(w, r, http.StatusUnprocessableEntity, problem.CodeValidationFailed, err.Error())
+	case errors.Is(err, ErrNotFound):
+		problem.Error(w, r, http.StatusNotFound, problem.CodeAPIKeyNotFound, "API key not found")
+	default:
+		problem.Internal(w, r, err)
+	}
+}
+
+// @Summary Create an API key
+// @Description Create a long-lived key for machine-to-machine clients, sent as the X-API-Key header. Scopes are rbac permissions (movies:read, movies:write); admin permissions such as movies:purge cannot be granted. The key is only returned in this response. Requires api_keys:manage (admin).
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param key body models.CreateAPIKeyRequest true "Name, scopes and optional expiry"
+// @Success 201 {object} models.CreateAPIKeyResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Router /api/api-keys [post]
+func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "Invalid JSON format")
+		return
+	}
+	createdBy := middleware.PrincipalFromContext(r.Context()).Username
+	resp, err := h.service.Create(r.Context(), req, createdBy)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	// Key hanya ditampilkan sekali, jangan sampai tersimpan di cache
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary List API keys
+// @Description List every API key, newest first, including revoked ones. Secrets are never returned. Requires api_keys:manage (admin).
+// @Tags api-keys
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIKeyListResponse
+// @Failure 403 {object} problem.Problem
+// @Router /api/api-keys [get]
+func (h *Handler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	resp, err := h.service.List(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary Revoke an API key
+// @Description Disable an API key immediately. The key stays listed with revoked_at set. Requires api_keys:manage (admin).
+// @Tags api-keys
+// @Security BearerAuth
+// @Param id path string true "API key ID"
+// @Success 204 {object} nil
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Router /api/api-keys/{id} [delete]
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.Revoke(r.Context(), mux.Vars(r)["id"]); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
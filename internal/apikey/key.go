@@ -0,0 +1,52 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// Format key: gf_<prefix>_<secret>. Prefix (8 hex) disimpan apa adanya untuk lookup
+// dan agar key mudah dikenali di log atau secret scanner; secret 32 byte acak.
+const (
+	keyMarker    = "gf"
+	prefixBytes  = 4
+	secretBytes  = 32
+	prefixLength = prefixBytes * 2
+)
+
+// newKey returns a new plaintext key with its prefix and hash
+func newKey() (key, prefix, hash string, err error) {
+	p := make([]byte, prefixBytes)
+	if _, err := rand.Read(p); err != nil {
+		return "", "", "", err
+	}
+	secret := make([]byte, secretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+	prefix = hex.EncodeToString(p)
+	key = keyMarker + "_" + prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	return key, prefix, hashKey(key), nil
+}
+
+// parsePrefix returns the prefix of key, or false if key does not look like an API key
+func parsePrefix(key string) (string, bool) {
+	parts := strings.SplitN(key, "_", 3)
+	if len(parts) != 3 || parts[0] != keyMarker || len(parts[1]) != prefixLength || parts[2] == "" {
+		return "", false
+	}
+	if _, err := hex.DecodeString(parts[1]); err != nil {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// hashKey returns the SHA-256 hex digest stored in api_keys.key_hash.
+// Key sudah acak 256 bit, jadi hash cepat sudah cukup (tidak perlu bcrypt).
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
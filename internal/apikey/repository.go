@@ -0,0 +1,79 @@
+package apikey
+
+import (
+	"context"
+	"time"
+
+	"go-flix-api/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(db *sqlx.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const apiKeyColumns = `id, name, prefix, key_hash, scopes, created_by, created_at, expires_at, last_used_at, revoked_at`
+
+const insertAPIKey = `INSERT INTO api_keys (id, name, prefix, key_hash, scopes, created_by, created_at, expires_at)
+	VALUES (:id, :name, :prefix, :key_hash, :scopes, :created_by, :created_at, :expires_at)`
+
+func (r *Repository) Create(ctx context.Context, k models.APIKey) error {
+	_, err := r.db.NamedExecContext(ctx, insertAPIKey, k)
+	return err
+}
+
+// FindByPrefix returns the key with the visible prefix, or ErrNotFound
+func (r *Repository) FindByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	var k models.APIKey
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE prefix = $1`
+	if err := r.db.GetContext(ctx, &k, query, prefix); err != nil {
+		return nil, translateError(err)
+	}
+	return &k, nil
+}
+
+// FindAll returns every key, newest first, including revoked ones
+func (r *Repository) FindAll(ctx context.Context) ([]models.APIKey, error) {
+	keys := []models.APIKey{}
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys ORDER BY created_at DESC`
+	if err := r.db.SelectContext(ctx, &keys, query); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Revoke sets revoked_at unless the key was already revoked. Returns ErrNotFound for an unknown ID.
+func (r *Repository) Revoke(ctx context.Context, id string, at time.Time) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE api_keys SET revoked_at = COALESCE(revoked_at, $2) WHERE id = $1`, id, at)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RevokeCreatedBy revokes every active key created by username and returns how many were revoked
+func (r *Repository) RevokeCreatedBy(ctx context.Context, username string, at time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE api_keys SET revoked_at = $2 WHERE LOWER(created_by) = LOWER($1) AND revoked_at IS NULL`, username, at)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// TouchLastUsed records that the key was used at
+func (r *Repository) TouchLastUsed(ctx context.Context, id string, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = $2 WHERE id = $1`, id, at)
+	return err
+}
@@ -0,0 +1,122 @@
+package apikey
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go-flix-api/internal/validation"
+	"go-flix-api/models"
+
+	"github.com/google/uuid"
+)
+
+// lastUsedResolution membatasi penulisan last_used_at: key yang dipakai terus-menerus
+// hanya di-update sekali per menit, bukan di setiap request
+const lastUsedResolution = time.Minute
+
+type Service struct {
+	repo *Repository
+	now  func() time.Time
+}
+
+func NewService(repo *Repository) *Service {
+	return &Service{repo: repo, now: time.Now}
+}
+
+// validate runs the request validation, wrapping failures in ErrValidation
+func validate(req interface{}) error {
+	if err := validation.Struct(req); err != nil {
+		return fmt.Errorf("%w: %w", ErrValidation, err)
+	}
+	return nil
+}
+
+// Create generates a new key. The plaintext key is only part of the returned response;
+// afterwards only its hash exists.
+func (s *Service) Create(ctx context.Context, req models.CreateAPIKeyRequest, createdBy string) (*models.CreateAPIKeyResponse, error) {
+	if err := validate(req); err != nil {
+		return nil, err
+	}
+	now := s.now()
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		return nil, fmt.Errorf("%w: expires_at must be in the future", ErrValidation)
+	}
+	key, prefix, hash, err := newKey()
+	if err != nil {
+		return nil, err
+	}
+	k := models.APIKey{
+		ID:        uuid.New(),
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   hash,
+		Scopes:    req.Scopes,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		ExpiresAt: req.ExpiresAt,
+	}
+	if err := s.repo.Create(ctx, k); err != nil {
+		return nil, err
+	}
+	return &models.CreateAPIKeyResponse{APIKey: k, Key: key}, nil
+}
+
+func (s *Service) List(ctx context.Context) (*models.APIKeyListResponse, error) {
+	keys, err := s.repo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &models.APIKeyListResponse{Data: keys}, nil
+}
+
+// Revoke disables a key immediately. Revoking twice is not an error.
+func (s *Service) Revoke(ctx context.Context, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrNotFound
+	}
+	return s.repo.Revoke(ctx, id, s.now())
+}
+
+// RevokeCreatedBy revokes every active key created by username, used when that account is deleted
+func (s *Service) RevokeCreatedBy(ctx context.Context, username string) (int64, error) {
+	return s.repo.RevokeCreatedBy(ctx, username, s.now())
+}
+
+// Authenticate returns the stored key matching the plaintext key.
+// Malformed, unknown, revoked and expired keys all return an error wrapping ErrInvalidKey.
+func (s *Service) Authenticate(ctx context.Context, key string) (*models.APIKey, error) {
+	prefix, ok := parsePrefix(key)
+	if !ok {
+		return nil, fmt.Errorf("%w: malformed key", ErrInvalidKey)
+	}
+	k, err := s.repo.FindByPrefix(ctx, prefix)
+	if errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("%w: unknown prefix %s", ErrInvalidKey, prefix)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashKey(key)), []byte(k.KeyHash)) != 1 {
+		return nil, fmt.Errorf("%w: wrong secret for prefix %s", ErrInvalidKey, prefix)
+	}
+	now := s.now()
+	if k.RevokedAt != nil {
+		return nil, fmt.Errorf("%w: key %s is revoked", ErrInvalidKey, prefix)
+	}
+	if k.ExpiresAt != nil && !now.Before(*k.ExpiresAt) {
+		return nil, fmt.Errorf("%w: key %s expired", ErrInvalidKey, prefix)
+	}
+	if k.LastUsedAt == nil || now.Sub(*k.LastUsedAt) >= lastUsedResolution {
+		// Gagal mencatat pemakaian tidak boleh menolak request
+		if err := s.repo.TouchLastUsed(ctx, k.ID.String(), now); err != nil {
+			slog.Warn("Gagal memperbarui last_used_at API key", "prefix", prefix, "error", err)
+		} else {
+			k.LastUsedAt = &now
+		}
+	}
+	return k, nil
+}
@@ -0,0 +1,156 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"go-flix-api/internal/middleware"
+	"go-flix-api/internal/rbac"
+	"go-flix-api/models"
+)
+
+var keyCols = []string{"id", "name", "prefix", "key_hash", "scopes", "created_by", "created_at", "expires_at", "last_used_at", "revoked_at"}
+
+func newMockService(t *testing.T) (*Service, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewService(NewRepository(sqlx.NewDb(db, "sqlmock"))), mock
+}
+
+var findQuery = regexp.QuoteMeta("FROM api_keys WHERE prefix = $1")
+
+func keyRow(key string, lastUsed, expires, revoked interface{}) *sqlmock.Rows {
+	prefix, _ := parsePrefix(key)
+	return sqlmock.NewRows(keyCols).AddRow(uuid.New(), "ingest", prefix, hashKey(key),
+		pq.StringArray{"movies:read", "movies:write"}, "admin", time.Now(), expires, lastUsed, revoked)
+}
+
+func TestKeyFormat(t *testing.T) {
+	key, prefix, hash, err := newKey()
+	if err != nil {
+		t.Fatalf("newKey: %v", err)
+	}
+	got, ok := parsePrefix(key)
+	if !ok || got != prefix || hash != hashKey(key) {
+		t.Fatalf("expected %q to parse to prefix %q, got %q ok=%v", key, prefix, got, ok)
+	}
+	for _, bad := range []string{"", "gf_", "gf_zzzzzzzz_secret", "xx_3f9a1c7e_secret", "gf_3f9a1c7e_", "Bearer abc"} {
+		if _, ok := parsePrefix(bad); ok {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestCreateValidatesScopes(t *testing.T) {
+	svc, mock := newMockService(t)
+	ctx := context.Background()
+
+	_, err := svc.Create(ctx, models.CreateAPIKeyRequest{Name: "ingest", Scopes: []string{"users:manage"}}, "admin")
+	if !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected admin permission to be refused as scope, got %v", err)
+	}
+	_, err = svc.Create(ctx, models.CreateAPIKeyRequest{Name: "ingest", Scopes: []string{"movies:read", "movies:purge"}}, "admin")
+	if !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected destructive movies:purge to be refused as scope, got %v", err)
+	}
+	past := time.Now().Add(-time.Hour)
+	_, err = svc.Create(ctx, models.CreateAPIKeyRequest{Name: "ingest", Scopes: []string{"movies:read"}, ExpiresAt: &past}, "admin")
+	if !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected past expiry to be refused, got %v", err)
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO api_keys")).WillReturnResult(sqlmock.NewResult(0, 1))
+	resp, err := svc.Create(ctx, models.CreateAPIKeyRequest{Name: "ingest", Scopes: []string{"movies:read", "movies:write"}}, "admin")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if prefix, ok := parsePrefix(resp.Key); !ok || prefix != resp.Prefix || resp.KeyHash != hashKey(resp.Key) {
+		t.Fatalf("expected key matching its prefix and hash, got %+v", resp)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	svc, mock := newMockService(t)
+	ctx := context.Background()
+	key, _, _, _ := newKey()
+	prefix, _ := parsePrefix(key)
+
+	// Pemakaian pertama mencatat last_used_at
+	mock.ExpectQuery(findQuery).WithArgs(prefix).WillReturnRows(keyRow(key, nil, nil, nil))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE api_keys SET last_used_at")).WillReturnResult(sqlmock.NewResult(0, 1))
+	if k, err := svc.Authenticate(ctx, key); err != nil || k.LastUsedAt == nil {
+		t.Fatalf("expected valid key with last_used_at, got %+v err=%v", k, err)
+	}
+
+	// Baru saja dipakai: tidak ada UPDATE lagi
+	mock.ExpectQuery(findQuery).WithArgs(prefix).WillReturnRows(keyRow(key, time.Now(), nil, nil))
+	if _, err := svc.Authenticate(ctx, key); err != nil {
+		t.Fatalf("expected valid key, got %v", err)
+	}
+
+	mock.ExpectQuery(findQuery).WithArgs(prefix).WillReturnRows(keyRow(key[:len(key)-1]+"x", time.Now(), nil, nil))
+	if _, err := svc.Authenticate(ctx, key); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("expected wrong secret rejected, got %v", err)
+	}
+	mock.ExpectQuery(findQuery).WithArgs(prefix).WillReturnRows(keyRow(key, time.Now(), nil, time.Now()))
+	if _, err := svc.Authenticate(ctx, key); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("expected revoked key rejected, got %v", err)
+	}
+	mock.ExpectQuery(findQuery).WithArgs(prefix).WillReturnRows(keyRow(key, time.Now(), time.Now().Add(-time.Minute), nil))
+	if _, err := svc.Authenticate(ctx, key); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("expected expired key rejected, got %v", err)
+	}
+	mock.ExpectQuery(findQuery).WithArgs(prefix).WillReturnRows(sqlmock.NewRows(keyCols))
+	if _, err := svc.Authenticate(ctx, key); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("expected unknown key rejected, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestStrategyGrantsScopes(t *testing.T) {
+	svc, mock := newMockService(t)
+	key, _, _, _ := newKey()
+	prefix, _ := parsePrefix(key)
+	mock.ExpectQuery(findQuery).WithArgs(prefix).WillReturnRows(keyRow(key, time.Now(), nil, nil))
+
+	strategy := NewStrategy(svc)
+	if _, err := strategy.Authenticate(httptest.NewRequest(http.MethodGet, "/api/movies", nil)); !errors.Is(err, middleware.ErrNoCredentials) {
+		t.Fatalf("expected no credentials without header, got %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/movies", nil)
+	req.Header.Set(Header, key)
+	p, err := strategy.Authenticate(req)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if p.Method != middleware.MethodAPIKey || p.Username != "apikey:"+prefix {
+		t.Fatalf("unexpected principal %+v", p)
+	}
+	if !p.Has(rbac.MoviesWrite) || p.Has(rbac.MoviesPurge) || p.Has(rbac.UsersManage) {
+		t.Fatalf("expected permissions from scopes only, got %v", p.Scopes)
+	}
+
+	req.Header.Set(Header, "gf_nothex!_secret")
+	if _, err := strategy.Authenticate(req); !errors.Is(err, middleware.ErrInvalidCredentials) {
+		t.Fatalf("expected malformed key to be invalid credentials, got %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package apikey
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"go-flix-api/internal/middleware"
+	"go-flix-api/internal/rbac"
+)
+
+// Header is the request header API keys are sent in
+const Header = "X-API-Key"
+
+// Strategy authenticates requests carrying an X-API-Key header.
+// The principal gets the key's scopes as permissions instead of a role.
+type Strategy struct {
+	service *Service
+}
+
+func NewStrategy(service *Service) *Strategy {
+	return &Strategy{service: service}
+}
+
+func (s *Strategy) Header() string {
+	return Header
+}
+
+func (s *Strategy) Authenticate(r *http.Request) (*middleware.Principal, error) {
+	key := r.Header.Get(Header)
+	if key == "" {
+		return nil, middleware.ErrNoCredentials
+	}
+	k, err := s.service.Authenticate(r.Context(), key)
+	if errors.Is(err, ErrInvalidKey) {
+		return nil, fmt.Errorf("%w: %v", middleware.ErrInvalidCredentials, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	scopes := make([]rbac.Permission, len(k.Scopes))
+	for i, scope := range k.Scopes {
+		scopes[i] = rbac.Permission(scope)
+	}
+	p := &middleware.Principal{
+		// Dicatat sebagai created_by/updated_by film, jadi dibuat unik dan mudah dilacak
+		Username: "apikey:" + k.Prefix,
+		Method:   middleware.MethodAPIKey,
+		Scopes:   scopes,
+	}
+	if k.ExpiresAt != nil {
+		p.ExpiresAt = *k.ExpiresAt
+	}
+	return p, nil
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Denylist menyimpan JTI token yang sudah di-logout sampai token tersebut kedaluwarsa.
+// Setelah kedaluwarsa, entri boleh dihapus oleh Sweep karena token-nya sudah ditolak oleh validasi exp.
+type Denylist interface {
+	Revoke(ctx context.Context, jti string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+	Sweeper
+}
+
+// Sweeper menghapus entri yang kedaluwarsa sebelum now dan mengembalikan jumlah yang dihapus.
+type Sweeper interface {
+	Sweep(ctx context.Context, now time.Time) (int64, error)
+}
+
+// --- Implementasi In-Memory ---
+
+// MemoryDenylist menyimpan denylist di memori proses.
+// Cocok untuk development dan test; logout hilang saat restart dan tidak dibagi antar replika.
+type MemoryDenylist struct {
+	mu      sync.RWMutex
+	entries map[string]time.Time
+}
+
+// NewMemoryDenylist membuat denylist in-memory yang kosong.
+func NewMemoryDenylist() *MemoryDenylist {
+	return &MemoryDenylist{entries: make(map[string]time.Time)}
+}
+
+func (d *MemoryDenylist) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.entries[jti] = expiresAt
+	return nil
+}
+
+func (d *MemoryDenylist) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	_, revoked := d.entries[jti]
+	return revoked, nil
+}
+
+func (d *MemoryDenylist) Sweep(ctx context.Context, now time.Time) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var n int64
+	for jti, exp := range d.entries {
+		if exp.Before(now) {
+			delete(d.entries, jti)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// --- Implementasi PostgreSQL ---
+
+// PostgresDenylist menyimpan denylist di tabel revoked_tokens,
+// sehingga logout bertahan saat restart dan berlaku di semua replika.
+type PostgresDenylist struct {
+	db *sqlx.DB
+}
+
+// NewPostgresDenylist membuat denylist yang memakai tabel revoked_tokens.
+func NewPostgresDenylist(db *sqlx.DB) *PostgresDenylist {
+	return &PostgresDenylist{db: db}
+}
+
+func (d *PostgresDenylist) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	query := `INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING`
+	_, err := d.db.ExecContext(ctx, query, jti, expiresAt)
+	return err
+}
+
+func (d *PostgresDenylist) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	var revoked bool
+	query := `SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)`
+	err := d.db.GetContext(ctx, &revoked, query, jti)
+	return revoked, err
+}
+
+func (d *PostgresDenylist) Sweep(ctx context.Context, now time.Time) (int64, error) {
+	result, err := d.db.ExecContext(ctx, `DELETE FROM revoked_tokens WHERE expires_at < $1`, now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// --- Janitor ---
+
+// StartJanitor menjalankan Sweep setiap interval di goroutine terpisah sampai ctx dibatalkan,
+// agar denylist dan refresh token tidak tumbuh tanpa batas.
+func StartJanitor(ctx context.Context, interval time.Duration, sweepers ...Sweeper) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				for _, s := range sweepers {
+					n, err := s.Sweep(ctx, now)
+					if err != nil {
+						slog.Error("Gagal membersihkan token kedaluwarsa", "store", fmt.Sprintf("%T", s), "error", err)
+						continue
+					}
+					if n > 0 {
+						slog.Info("Token kedaluwarsa dibersihkan", "store", fmt.Sprintf("%T", s), "removed", n)
+					}
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+)
+
+func TestMemoryDenylistSweep(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	d := NewMemoryDenylist()
+	_ = d.Revoke(ctx, "expired", now.Add(-time.Minute))
+	_ = d.Revoke(ctx, "active", now.Add(time.Hour))
+
+	n, err := d.Sweep(ctx, now)
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 entry swept, got n=%d err=%v", n, err)
+	}
+	if revoked, _ := d.IsRevoked(ctx, "expired"); revoked {
+		t.Fatalf("expected expired entry removed")
+	}
+	if revoked, _ := d.IsRevoked(ctx, "active"); !revoked {
+		t.Fatalf("expected active entry kept")
+	}
+}
+
+func TestPostgresDenylist(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+	d := NewPostgresDenylist(sqlx.NewDb(db, "postgres"))
+	ctx := context.Background()
+	exp := time.Now().Add(time.Hour)
+
+	mock.ExpectExec(`INSERT INTO revoked_tokens \(jti, expires_at\) VALUES \(\$1, \$2\) ON CONFLICT \(jti\) DO NOTHING`).
+		WithArgs("abc", exp).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := d.Revoke(ctx, "abc", exp); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM revoked_tokens WHERE jti = \$1\)`).
+		WithArgs("abc").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	revoked, err := d.IsRevoked(ctx, "abc")
+	if err != nil || !revoked {
+		t.Fatalf("expected revoked, got revoked=%v err=%v", revoked, err)
+	}
+
+	now := time.Now()
+	mock.ExpectExec(`DELETE FROM revoked_tokens WHERE expires_at < \$1`).
+		WithArgs(now).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	n, err := d.Sweep(ctx, now)
+	if err != nil || n != 3 {
+		t.Fatalf("expected 3 swept, got n=%d err=%v", n, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -1,12 +1,20 @@
 package auth
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"go-flix-api/config"
+	"go-flix-api/internal/middleware"
+	"go-flix-api/internal/problem"
+	"go-flix-api/internal/signing"
+	"go-flix-api/internal/user"
+	"go-flix-api/models"
+	"log/slog"
+	"math"
 	"net/http"
-	"strings"
-	"sync"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -17,9 +25,13 @@ import (
 
 // Service adalah tempat semua logika bisnis inti.
 type Service struct {
-	cfg      *config.Config
-	denylist map[string]time.Time // Database token yang sudah di-logout
-	mu       sync.RWMutex
+	cfg      atomic.Pointer[config.Config] // diganti utuh oleh SetConfig saat config di-reload
+	keys     *signing.Keyring
+	users    UserStore
+	denylist Denylist       // Penyimpanan token yang sudah di-logout
+	refresh  RefreshStore   // Penyimpanan refresh token (hanya hash)
+	throttle *LoginThrottle // Pembatas tebakan password
+	now      func() time.Time
 }
 
 // Handler adalah lapisan HTTP yang menerima request.
@@ -27,20 +39,48 @@ type Handler struct {
 	service *Service
 }
 
-// JWTClaims adalah data yang kita simpan di dalam token.
-type JWTClaims struct {
-	Username string `json:"username"`
-	jwt.RegisteredClaims
+// TokenResponse adalah body response login dan refresh.
+type TokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type" example:"Bearer"`
+	ExpiresIn    int    `json:"expires_in" example:"3600"` // umur access token dalam detik
 }
 
+// UserStore memberi akses ke akun di database. Dipenuhi oleh user.Service.
+type UserStore interface {
+	// Authenticate mengembalikan user.ErrInvalidCredentials jika username atau password salah.
+	Authenticate(ctx context.Context, username, password string) (*models.User, error)
+	// GetByUsername mengembalikan user.ErrNotFound jika akun sudah tidak ada.
+	GetByUsername(ctx context.Context, username string) (*models.User, error)
+}
+
+// Umur default token jika tidak diatur di config.
+const (
+	defaultAccessTTL  = 1 * time.Hour
+	defaultRefreshTTL = 30 * 24 * time.Hour
+)
+
 // --- Konstruktor (Fungsi "Pabrik") ---
 
 // NewService membuat instance baru dari Service.
-func NewService(cfg *config.Config) *Service {
-	return &Service{
-		cfg:      cfg,
-		denylist: make(map[string]time.Time),
+func NewService(cfg *config.Config, keys *signing.Keyring, users UserStore, denylist Denylist, refresh RefreshStore, throttle *LoginThrottle) *Service {
+	s := &Service{
+		keys:     keys,
+		users:    users,
+		denylist: denylist,
+		refresh:  refresh,
+		throttle: throttle,
+		now:      time.Now,
 	}
+	s.cfg.Store(cfg)
+	return s
+}
+
+// SetConfig swaps in a reloaded configuration. Request yang sedang berjalan tetap
+// memakai config lama; kunci JWT dirotasi terpisah lewat Keyring.Rotate.
+func (s *Service) SetConfig(cfg *config.Config) {
+	s.cfg.Store(cfg)
 }
 
 // NewHandler membuat instance baru dari Handler.
@@ -52,69 +92,191 @@ func NewHandler(service *Service) *Handler {
 
 // --- Method-Method Service (Logika Inti) ---
 
-// ValidateUser memeriksa username & password.
-func (s *Service) ValidateUser(username, password string) bool {
-	for _, u := range s.cfg.Users {
-		if u.Username == username && u.Password == password {
-			return true
-		}
+func (s *Service) accessTTL() time.Duration {
+	if ttl := s.cfg.Load().JWT.AccessTTL; ttl > 0 {
+		return ttl
 	}
-	return false
+	return defaultAccessTTL
 }
 
-// GenerateJWT membuat token JWT baru.
-func (s *Service) GenerateJWT(username string) (string, error) {
-	claims := JWTClaims{
-		Username: username,
+func (s *Service) refreshTTL() time.Duration {
+	if ttl := s.cfg.Load().JWT.RefreshTTL; ttl > 0 {
+		return ttl
+	}
+	return defaultRefreshTTL
+}
+
+// GenerateJWT membuat access token JWT baru untuk sesi (family refresh token) sessionID.
+func (s *Service) GenerateJWT(username, role, sessionID string) (string, error) {
+	now := s.now()
+	claims := signing.Claims{
+		Username:  username,
+		Role:      role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(1 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.accessTTL())),
+			IssuedAt:  jwt.NewNumericDate(now),
 			ID:        uuid.New().String(), // ID unik untuk setiap token
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.cfg.JWT.Secret))
+	s.keys.Stamp(&claims.RegisteredClaims)
+	return s.keys.Sign(claims)
 }
 
-// RevokeToken menambahkan token ke denylist (untuk logout).
-func (s *Service) RevokeToken(tokenStr string) error {
-	claims := &JWTClaims{}
-	// PERBAIKAN PENTING: Kita tetap validasi token sebelum di-logout untuk keamanan.
-	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(s.cfg.JWT.Secret), nil
-	})
-	if err != nil || !token.Valid {
-		return errors.New("invalid token")
+// IssueTokens membuat access token dan refresh token untuk login baru (family baru).
+func (s *Service) IssueTokens(ctx context.Context, account *models.User) (*TokenResponse, error) {
+	return s.issueTokens(ctx, account, uuid.New().String())
+}
+
+func (s *Service) issueTokens(ctx context.Context, account *models.User, familyID string) (*TokenResponse, error) {
+	refreshToken, hash, err := newRefreshSecret()
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	record := &RefreshToken{
+		ID:        uuid.New().String(),
+		FamilyID:  familyID,
+		Username:  account.Username,
+		TokenHash: hash,
+		ExpiresAt: now.Add(s.refreshTTL()),
+		CreatedAt: now,
+	}
+	if err := s.refresh.Create(ctx, record); err != nil {
+		return nil, err
 	}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	accessToken, err := s.GenerateJWT(account.Username, account.Role, familyID)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.accessTTL().Seconds()),
+	}, nil
+}
+
+// RefreshTokens menukar refresh token dengan pasangan token baru (rotasi).
+// Refresh token yang sudah pernah ditukar dianggap dicuri: seluruh family dicabut
+// dan ErrRefreshTokenReused dikembalikan.
+func (s *Service) RefreshTokens(ctx context.Context, refreshToken string) (*TokenResponse, error) {
+	record, err := s.refresh.FindByHash(ctx, hashRefreshToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	if record.RevokedAt != nil || !now.Before(record.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if record.RotatedAt != nil {
+		return nil, s.revokeReusedFamily(ctx, record, now)
+	}
+
+	rotated, err := s.refresh.MarkRotated(ctx, record.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// Request lain sudah menukar token ini lebih dulu
+		return nil, s.revokeReusedFamily(ctx, record, now)
+	}
+	// Role dibaca ulang dari database agar perubahan role berlaku saat refresh berikutnya
+	account, err := s.users.GetByUsername(ctx, record.Username)
+	if errors.Is(err, user.ErrNotFound) {
+		// Akun sudah dihapus: sesi ini tidak boleh diperpanjang
+		if err := s.refresh.RevokeFamily(ctx, record.FamilyID, now); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.issueTokens(ctx, account, record.FamilyID)
+}
+
+func (s *Service) revokeReusedFamily(ctx context.Context, record *RefreshToken, now time.Time) error {
+	slog.Warn("Refresh token dipakai ulang, family dicabut", "username", record.Username, "family_id", record.FamilyID)
+	if err := s.refresh.RevokeFamily(ctx, record.FamilyID, now); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// Logout mencabut access token milik principal beserta refresh token dari sesi yang sama.
+// Principal harus berasal dari access token (Bearer atau cookie).
+func (s *Service) Logout(ctx context.Context, p middleware.Principal) error {
+	if p.TokenID == "" {
+		return errors.New("principal was not authenticated with an access token")
+	}
+	return s.revokeSession(ctx, p.TokenID, p.ExpiresAt, p.SessionID)
+}
+
+func (s *Service) revokeSession(ctx context.Context, jti string, expiresAt time.Time, sessionID string) error {
 	// Simpan ID token dan waktu kedaluwarsanya.
-	s.denylist[claims.ID] = claims.ExpiresAt.Time
+	if err := s.denylist.Revoke(ctx, jti, expiresAt); err != nil {
+		return err
+	}
+	// Cabut juga refresh token dari sesi yang sama agar tidak bisa dipakai login ulang
+	if sessionID != "" {
+		return s.refresh.RevokeFamily(ctx, sessionID, s.now())
+	}
 	return nil
 }
 
+// setSessionCookie menyimpan access token di cookie bila jwt.session_cookie diisi
+func (h *Handler) setSessionCookie(w http.ResponseWriter, tokens *TokenResponse) {
+	name := h.service.cfg.Load().JWT.SessionCookie
+	if name == "" {
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    tokens.Token,
+		Path:     "/api",
+		MaxAge:   tokens.ExpiresIn,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+func (h *Handler) clearSessionCookie(w http.ResponseWriter) {
+	name := h.service.cfg.Load().JWT.SessionCookie
+	if name == "" {
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: name, Path: "/api", MaxAge: -1, HttpOnly: true, Secure: true, SameSite: http.SameSiteStrictMode})
+}
+
 // IsTokenRevoked memeriksa apakah token ada di denylist.
-// Ini akan dipanggil oleh middleware.
-func (s *Service) IsTokenRevoked(jti string) bool {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	_, revoked := s.denylist[jti]
-	return revoked
+func (s *Service) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	return s.denylist.IsRevoked(ctx, jti)
 }
 
 // --- Method-Method Handler (Lapisan HTTP) ---
 
+// securityEvent mencatat kejadian keamanan sebagai log terstruktur dengan field "event",
+// sehingga mudah difilter dan dijadikan alert
+func securityEvent(r *http.Request, event string, attrs ...any) {
+	attrs = append([]any{"event", event, "request_id", r.Header.Get(problem.RequestIDHeader)}, attrs...)
+	slog.Warn("Security event", attrs...)
+}
+
 // @Summary User login
-// @Description Authenticate user and return JWT token
+// @Description Authenticate user and return a JWT access token plus a refresh token
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param credentials body object{username=string,password=string} true "Login credentials"
-// @Success 200 {object} map[string]string "token"
-// @Failure 400 {object} map[string]string "Invalid JSON"
-// @Failure 401 {object} map[string]string "Invalid credentials"
-// @Failure 500 {object} map[string]string "Failed to generate token"
+// @Success 200 {object} TokenResponse
+// @Failure 400 {object} problem.Problem "Invalid JSON"
+// @Failure 401 {object} problem.Problem "Invalid credentials"
+// @Failure 429 {object} problem.Problem "Too many failed attempts for this username or IP; see Retry-After"
+// @Header 429 {integer} Retry-After "Seconds until the next attempt is allowed"
+// @Failure 500 {object} problem.Problem "Failed to generate token"
 // @Router /api/login [post]
 // Login menangani POST /api/login.
 func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
@@ -124,50 +286,137 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 	var req LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "Invalid JSON")
 		return
 	}
 
-	// Memanggil service untuk validasi.
-	if !h.service.ValidateUser(req.Username, req.Password) {
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+	ctx := r.Context()
+	ip := middleware.ClientIP(r)
+	// Cek throttle sebelum bcrypt, agar tebakan yang ditolak juga tidak membebani CPU
+	if wait, err := h.service.throttle.Check(ctx, req.Username, ip); err != nil {
+		problem.Internal(w, r, err)
+		return
+	} else if wait > 0 {
+		securityEvent(r, "login_throttled", "username", req.Username, "ip", ip, "retry_after", wait.String())
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		problem.Error(w, r, http.StatusTooManyRequests, problem.CodeLoginThrottled, "Too many failed login attempts, try again later")
+		return
+	}
+
+	// Memanggil service user untuk validasi password (bcrypt).
+	account, err := h.service.users.Authenticate(ctx, req.Username, req.Password)
+	if errors.Is(err, user.ErrInvalidCredentials) {
+		result, err := h.service.throttle.Fail(ctx, req.Username, ip)
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		securityEvent(r, "login_failed", "username", req.Username, "ip", ip,
+			"user_failures", result.UserFailures, "ip_failures", result.IPFailures)
+		if result.Locked {
+			securityEvent(r, "login_locked", "username", req.Username, "ip", ip,
+				"user_failures", result.UserFailures, "ip_failures", result.IPFailures)
+		}
+		problem.Error(w, r, http.StatusUnauthorized, problem.CodeInvalidCredentials, "Invalid credentials")
+		return
+	}
+	if err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+	if err := h.service.throttle.Succeed(ctx, req.Username); err != nil {
+		problem.Internal(w, r, err)
 		return
 	}
 
-	// Memanggil service untuk membuat token.
-	tokenStr, err := h.service.GenerateJWT(req.Username)
+	// Memanggil service untuk membuat token. Username dan role diambil dari database.
+	tokens, err := h.service.IssueTokens(ctx, account)
 	if err != nil {
-		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		problem.Internal(w, r, err)
 		return
 	}
 
+	h.setSessionCookie(w, tokens)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// @Summary Refresh access token
+// @Description Exchange a refresh token for a new access token and a new refresh token. Each refresh token can be used once; replaying an old one revokes the whole session.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param body body object{refresh_token=string} true "Refresh token"
+// @Success 200 {object} TokenResponse
+// @Failure 400 {object} problem.Problem "Invalid JSON"
+// @Failure 401 {object} problem.Problem "Invalid, expired or reused refresh token"
+// @Router /api/token/refresh [post]
+// Refresh menangani POST /api/token/refresh.
+func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "Invalid JSON")
+		return
+	}
+
+	tokens, err := h.service.RefreshTokens(r.Context(), req.RefreshToken)
+	switch {
+	case errors.Is(err, ErrRefreshTokenReused):
+		problem.Error(w, r, http.StatusUnauthorized, problem.CodeRefreshTokenReused, "Refresh token was already used; the session has been revoked")
+		return
+	case errors.Is(err, ErrInvalidRefreshToken):
+		problem.Error(w, r, http.StatusUnauthorized, problem.CodeInvalidRefreshToken, "Invalid or expired refresh token")
+		return
+	case err != nil:
+		problem.Internal(w, r, err)
+		return
+	}
+
+	h.setSessionCookie(w, tokens)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// @Summary JSON Web Key Set
+// @Description Public keys for verifying access tokens offline, selected by the token's kid header. Empty when tokens are HS256-signed.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} signing.JWKS
+// @Router /.well-known/jwks.json [get]
+// JWKS menangani GET /.well-known/jwks.json.
+func (h *Handler) JWKS(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"token": tokenStr})
+	// Boleh di-cache sebentar; kunci baru dipublikasikan sebelum dipakai menandatangani
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(h.service.keys.JWKS())
 }
 
 // @Summary User logout
-// @Description Revoke JWT token (logout)
+// @Description Revoke the JWT access token and every refresh token of its session (logout)
 // @Tags auth
 // @Produce json
 // @Security BearerAuth
 // @Success 200 {object} map[string]string "message"
-// @Failure 401 {object} map[string]string "Missing or invalid Authorization header"
+// @Failure 400 {object} problem.Problem "Not authenticated with an access token"
+// @Failure 401 {object} problem.Problem "Missing, invalid or revoked token"
 // @Router /api/logout [post]
-// Logout menangani POST /api/logout.
+// Logout menangani POST /api/logout. Token sudah diverifikasi oleh Authenticator.
 func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-		http.Error(w, "Missing or invalid Authorization header", http.StatusUnauthorized)
+	principal := middleware.PrincipalFromContext(r.Context())
+	if principal.TokenID == "" {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeBadRequest, "Logout requires an access token")
 		return
 	}
-	tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
 
 	// Memanggil service untuk me-revoke token.
-	if err := h.service.RevokeToken(tokenStr); err != nil {
-		http.Error(w, "Invalid token for logout", http.StatusUnauthorized)
+	if err := h.service.Logout(r.Context(), principal); err != nil {
+		problem.Internal(w, r, err)
 		return
 	}
 
+	h.clearSessionCookie(w)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "logout successful"})
 }
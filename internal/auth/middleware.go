@@ -1,48 +0,0 @@
-package auth
-
-import (
-	"context"
-	"net/http"
-	"strings"
-
-	"github.com/golang-jwt/jwt/v5"
-)
-
-// AuthMiddleware sekarang menerima AuthService sebagai argumen
-func AuthMiddleware(authService *Service, jwtSecret string) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			authHeader := r.Header.Get("Authorization")
-			if !strings.HasPrefix(authHeader, "Bearer ") {
-				http.Error(w, "Missing or invalid Authorization header", http.StatusUnauthorized)
-				return
-			}
-
-			tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-			claims := &JWTClaims{}
-
-			token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-				// Pastikan method signing-nya benar
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, http.ErrAbortHandler
-				}
-				return []byte(jwtSecret), nil
-			})
-
-			if err != nil || !token.Valid {
-				http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
-				return
-			}
-
-			// PERBAIKAN DI SINI: Panggil method dari authService yang sudah kita berikan
-			if authService.IsTokenRevoked(claims.ID) {
-				http.Error(w, "Token has been revoked (logged out)", http.StatusUnauthorized)
-				return
-			}
-
-			// Inject username ke dalam context request agar bisa digunakan oleh handler selanjutnya
-			ctx := context.WithValue(r.Context(), "username", claims.Username)
-			next.ServeHTTP(w, r.WithContext(ctx))
-		})
-	}
-}
\ No newline at end of file
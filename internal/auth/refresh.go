@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	// ErrInvalidRefreshToken dikembalikan untuk refresh token yang tidak dikenal, kedaluwarsa atau sudah dicabut
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused dikembalikan saat refresh token yang sudah dirotasi dipakai lagi.
+	// Seluruh family sudah dicabut ketika error ini muncul.
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+)
+
+// RefreshToken adalah catatan refresh token di server.
+// Token aslinya tidak pernah disimpan, hanya hash SHA-256-nya.
+// Semua token hasil rotasi dari satu login berbagi FamilyID yang sama.
+type RefreshToken struct {
+	ID        string     `db:"id"`
+	FamilyID  string     `db:"family_id"`
+	Username  string     `db:"username"`
+	TokenHash string     `db:"token_hash"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	CreatedAt time.Time  `db:"created_at"`
+	RotatedAt *time.Time `db:"rotated_at"` // diisi saat token ditukar dengan token baru
+	RevokedAt *time.Time `db:"revoked_at"` // diisi saat family dicabut (logout atau reuse)
+}
+
+// RefreshStore menyimpan refresh token yang sudah di-hash.
+type RefreshStore interface {
+	Create(ctx context.Context, token *RefreshToken) error
+	// FindByHash mengembalikan ErrInvalidRefreshToken jika hash tidak ditemukan.
+	FindByHash(ctx context.Context, hash string) (*RefreshToken, error)
+	// MarkRotated menandai token sudah dirotasi. Mengembalikan false jika token
+	// sudah dirotasi atau dicabut lebih dulu (mis. dua request refresh bersamaan).
+	MarkRotated(ctx context.Context, id string, at time.Time) (bool, error)
+	RevokeFamily(ctx context.Context, familyID string, at time.Time) error
+	// RevokeUser mencabut semua family milik username (tanpa membedakan huruf besar/kecil).
+	RevokeUser(ctx context.Context, username string, at time.Time) error
+	Sweep(ctx context.Context, now time.Time) (int64, error)
+}
+
+// newRefreshSecret membuat refresh token acak (256 bit) beserta hash-nya untuk disimpan.
+func newRefreshSecret() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashRefreshToken(token), nil
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// --- Implementasi In-Memory ---
+
+// MemoryRefreshStore menyimpan refresh token di memori proses (untuk development dan test).
+type MemoryRefreshStore struct {
+	mu     sync.Mutex
+	tokens map[string]*RefreshToken // key: token hash
+}
+
+// NewMemoryRefreshStore membuat store in-memory yang kosong.
+func NewMemoryRefreshStore() *MemoryRefreshStore {
+	return &MemoryRefreshStore{tokens: make(map[string]*RefreshToken)}
+}
+
+func (s *MemoryRefreshStore) Create(ctx context.Context, token *RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := *token
+	s.tokens[t.TokenHash] = &t
+	return nil
+}
+
+func (s *MemoryRefreshStore) FindByHash(ctx context.Context, hash string) (*RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tokens[hash]
+	if !ok {
+		return nil, ErrInvalidRefreshToken
+	}
+	cp := *t
+	return &cp, nil
+}
+
+func (s *MemoryRefreshStore) MarkRotated(ctx context.Context, id string, at time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.tokens {
+		if t.ID != id {
+			continue
+		}
+		if t.RotatedAt != nil || t.RevokedAt != nil {
+			return false, nil
+		}
+		t.RotatedAt = &at
+		return true, nil
+	}
+	return false, nil
+}
+
+func (s *MemoryRefreshStore) RevokeFamily(ctx context.Context, familyID string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.tokens {
+		if t.FamilyID == familyID && t.RevokedAt == nil {
+			t.RevokedAt = &at
+		}
+	}
+	return nil
+}
+
+func (s *MemoryRefreshStore) RevokeUser(ctx context.Context, username string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.tokens {
+		if strings.EqualFold(t.Username, username) && t.RevokedAt == nil {
+			t.RevokedAt = &at
+		}
+	}
+	return nil
+}
+
+func (s *MemoryRefreshStore) Sweep(ctx context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for hash, t := range s.tokens {
+		if t.ExpiresAt.Before(now) {
+			delete(s.tokens, hash)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// --- Implementasi PostgreSQL ---
+
+// PostgresRefreshStore menyimpan refresh token di tabel refresh_tokens.
+type PostgresRefreshStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresRefreshStore membuat store yang memakai tabel refresh_tokens.
+func NewPostgresRefreshStore(db *sqlx.DB) *PostgresRefreshStore {
+	return &PostgresRefreshStore{db: db}
+}
+
+func (s *PostgresRefreshStore) Create(ctx context.Context, token *RefreshToken) error {
+	query := `INSERT INTO refresh_tokens (id, family_id, username, token_hash, expires_at, created_at)
+		VALUES (:id, :family_id, :username, :token_hash, :expires_at, :created_at)`
+	_, err := s.db.NamedExecContext(ctx, query, token)
+	return err
+}
+
+func (s *PostgresRefreshStore) FindByHash(ctx context.Context, hash string) (*RefreshToken, error) {
+	var t RefreshToken
+	query := `SELECT id, family_id, username, token_hash, expires_at, created_at, rotated_at, revoked_at
+		FROM refresh_tokens WHERE token_hash = $1`
+	err := s.db.GetContext(ctx, &t, query, hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (s *PostgresRefreshStore) MarkRotated(ctx context.Context, id string, at time.Time) (bool, error) {
+	// Kondisi rotated_at IS NULL membuat rotasi atomik: hanya satu request yang menang
+	query := `UPDATE refresh_tokens SET rotated_at = $2
+		WHERE id = $1 AND rotated_at IS NULL AND revoked_at IS NULL`
+	result, err := s.db.ExecContext(ctx, query, id, at)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+func (s *PostgresRefreshStore) RevokeFamily(ctx context.Context, familyID string, at time.Time) error {
+	query := `UPDATE refresh_tokens SET revoked_at = $2 WHERE family_id = $1 AND revoked_at IS NULL`
+	_, err := s.db.ExecContext(ctx, query, familyID, at)
+	return err
+}
+
+func (s *PostgresRefreshStore) RevokeUser(ctx context.Context, username string, at time.Time) error {
+	query := `UPDATE refresh_tokens SET revoked_at = $2 WHERE LOWER(username) = LOWER($1) AND revoked_at IS NULL`
+	_, err := s.db.ExecContext(ctx, query, username, at)
+	return err
+}
+
+func (s *PostgresRefreshStore) Sweep(ctx context.Context, now time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE expires_at < $1`, now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"context"
+	"time"
+)
+
+// SessionRevoker mengakhiri sesi di luar alur logout, mis. saat password, role atau akun
+// seorang user diubah oleh dirinya sendiri atau admin.
+type SessionRevoker struct {
+	denylist Denylist
+	refresh  RefreshStore
+	now      func() time.Time
+}
+
+func NewSessionRevoker(denylist Denylist, refresh RefreshStore) *SessionRevoker {
+	return &SessionRevoker{denylist: denylist, refresh: refresh, now: time.Now}
+}
+
+// RevokeUser mencabut semua refresh token family milik username sehingga tidak ada
+// sesi yang bisa diperpanjang. Access token yang sudah terbit ditolak lewat
+// users.tokens_valid_after (lihat middleware.TokenCutoff).
+func (r *SessionRevoker) RevokeUser(ctx context.Context, username string) error {
+	return r.refresh.RevokeUser(ctx, username, r.now())
+}
+
+// RevokeAccessToken memasukkan satu access token ke denylist sampai expiresAt
+func (r *SessionRevoker) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	return r.denylist.Revoke(ctx, jti, expiresAt)
+}
@@ -0,0 +1,276 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"go-flix-api/config"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Default kebijakan login throttle, dipakai bila config login_throttle tidak diisi
+const (
+	defaultFreeAttempts       = 3
+	defaultBaseDelay          = time.Second
+	defaultMaxDelay           = time.Minute
+	defaultLockoutThreshold   = 10
+	defaultIPLockoutThreshold = 50
+	defaultLockoutDuration    = 15 * time.Minute
+)
+
+// Attempts adalah jumlah login gagal berturut-turut untuk satu key (username atau IP)
+type Attempts struct {
+	Failures    int       `db:"failures"`
+	LastFailure time.Time `db:"last_failure_at"`
+}
+
+// AttemptStore menyimpan hitungan login gagal. Entri kedaluwarsa window setelah kegagalan terakhir,
+// sehingga hitungan mulai dari nol lagi dan Sweep boleh menghapusnya.
+type AttemptStore interface {
+	// Get mengembalikan hitungan untuk key, atau Attempts kosong jika tidak ada/kedaluwarsa
+	Get(ctx context.Context, key string, now time.Time) (Attempts, error)
+	// Fail menambah satu kegagalan secara atomik dan mengembalikan hitungan terbaru
+	Fail(ctx context.Context, key string, now time.Time, window time.Duration) (Attempts, error)
+	// Reset menghapus hitungan key (setelah login berhasil)
+	Reset(ctx context.Context, key string) error
+	Sweeper
+}
+
+// LoginThrottle memperlambat tebakan password per username dan per IP.
+// Setelah FreeAttempts kegagalan, login berikutnya harus menunggu BaseDelay yang berlipat dua
+// setiap kegagalan (maksimal MaxDelay); setelah LockoutThreshold kegagalan, key dikunci
+// selama LockoutDuration. Hitungan dilupakan LockoutDuration setelah kegagalan terakhir.
+type LoginThrottle struct {
+	store              AttemptStore
+	freeAttempts       int
+	baseDelay          time.Duration
+	maxDelay           time.Duration
+	lockoutThreshold   int
+	ipLockoutThreshold int
+	lockoutDuration    time.Duration
+	now                func() time.Time
+}
+
+// NewLoginThrottle membuat throttle dari cfg; field yang kosong memakai default di atas
+func NewLoginThrottle(store AttemptStore, cfg config.LoginThrottleConfig) *LoginThrottle {
+	t := &LoginThrottle{
+		store:              store,
+		freeAttempts:       cfg.FreeAttempts,
+		baseDelay:          cfg.BaseDelay,
+		maxDelay:           cfg.MaxDelay,
+		lockoutThreshold:   cfg.LockoutThreshold,
+		ipLockoutThreshold: cfg.IPLockoutThreshold,
+		lockoutDuration:    cfg.LockoutDuration,
+		now:                time.Now,
+	}
+	if t.freeAttempts <= 0 {
+		t.freeAttempts = defaultFreeAttempts
+	}
+	if t.baseDelay <= 0 {
+		t.baseDelay = defaultBaseDelay
+	}
+	if t.maxDelay <= 0 {
+		t.maxDelay = defaultMaxDelay
+	}
+	if t.lockoutThreshold <= 0 {
+		t.lockoutThreshold = defaultLockoutThreshold
+	}
+	if t.ipLockoutThreshold <= 0 {
+		t.ipLockoutThreshold = defaultIPLockoutThreshold
+	}
+	if t.lockoutDuration <= 0 {
+		t.lockoutDuration = defaultLockoutDuration
+	}
+	return t
+}
+
+// Username tidak membedakan huruf besar/kecil, sama seperti lookup di tabel users
+func userKey(username string) string { return "user:" + strings.ToLower(username) }
+func ipKey(ip string) string         { return "ip:" + ip }
+
+// wait returns how long a key with attempts a must wait before the next login attempt
+func (t *LoginThrottle) wait(a Attempts, lockoutThreshold int, now time.Time) time.Duration {
+	var delay time.Duration
+	switch {
+	case a.Failures >= lockoutThreshold:
+		delay = t.lockoutDuration
+	case a.Failures >= t.freeAttempts:
+		delay = t.maxDelay
+		// Geser hanya selama tidak melampaui maxDelay (sekaligus mencegah overflow)
+		if shift := a.Failures - t.freeAttempts; shift < 32 && t.baseDelay<<shift < t.maxDelay {
+			delay = t.baseDelay << shift
+		}
+	default:
+		return 0
+	}
+	if remaining := a.LastFailure.Add(delay).Sub(now); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// Check returns how long the caller must wait before trying username from ip, or 0 if it may try now
+func (t *LoginThrottle) Check(ctx context.Context, username, ip string) (time.Duration, error) {
+	now := t.now()
+	user, err := t.store.Get(ctx, userKey(username), now)
+	if err != nil {
+		return 0, err
+	}
+	addr, err := t.store.Get(ctx, ipKey(ip), now)
+	if err != nil {
+		return 0, err
+	}
+	return max(t.wait(user, t.lockoutThreshold, now), t.wait(addr, t.ipLockoutThreshold, now)), nil
+}
+
+// FailureResult describes the state after a failed login
+type FailureResult struct {
+	UserFailures int
+	IPFailures   int
+	Locked       bool // kegagalan ini membuat username atau IP terkunci
+}
+
+// Fail records a failed login for username and ip
+func (t *LoginThrottle) Fail(ctx context.Context, username, ip string) (FailureResult, error) {
+	now := t.now()
+	user, err := t.store.Fail(ctx, userKey(username), now, t.lockoutDuration)
+	if err != nil {
+		return FailureResult{}, err
+	}
+	addr, err := t.store.Fail(ctx, ipKey(ip), now, t.lockoutDuration)
+	if err != nil {
+		return FailureResult{}, err
+	}
+	return FailureResult{
+		UserFailures: user.Failures,
+		IPFailures:   addr.Failures,
+		Locked:       user.Failures == t.lockoutThreshold || addr.Failures == t.ipLockoutThreshold,
+	}, nil
+}
+
+// RecordFailure is Fail for callers outside this package that only need to know whether
+// the failure locked username or ip, e.g. user.Handler for PUT /api/me/password
+func (t *LoginThrottle) RecordFailure(ctx context.Context, username, ip string) (locked bool, err error) {
+	result, err := t.Fail(ctx, username, ip)
+	return result.Locked, err
+}
+
+// Succeed clears the failures of username. Hitungan IP sengaja tidak di-reset,
+// agar penyerang tidak bisa menghapusnya dengan login ke akunnya sendiri.
+func (t *LoginThrottle) Succeed(ctx context.Context, username string) error {
+	return t.store.Reset(ctx, userKey(username))
+}
+
+// --- Implementasi In-Memory ---
+
+type memoryAttempt struct {
+	Attempts
+	expiresAt time.Time
+}
+
+// MemoryAttemptStore menyimpan hitungan di memori proses.
+// Cocok untuk development dan satu instance; setiap replika punya hitungan sendiri.
+type MemoryAttemptStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryAttempt
+}
+
+// NewMemoryAttemptStore membuat store in-memory yang kosong.
+func NewMemoryAttemptStore() *MemoryAttemptStore {
+	return &MemoryAttemptStore{entries: make(map[string]memoryAttempt)}
+}
+
+func (s *MemoryAttemptStore) Get(ctx context.Context, key string, now time.Time) (Attempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok || !now.Before(e.expiresAt) {
+		return Attempts{}, nil
+	}
+	return e.Attempts, nil
+}
+
+func (s *MemoryAttemptStore) Fail(ctx context.Context, key string, now time.Time, window time.Duration) (Attempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.entries[key]
+	if !now.Before(e.expiresAt) {
+		e = memoryAttempt{}
+	}
+	e.Failures++
+	e.LastFailure = now
+	e.expiresAt = now.Add(window)
+	s.entries[key] = e
+	return e.Attempts, nil
+}
+
+func (s *MemoryAttemptStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+func (s *MemoryAttemptStore) Sweep(ctx context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for key, e := range s.entries {
+		if e.expiresAt.Before(now) {
+			delete(s.entries, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// --- Implementasi PostgreSQL ---
+
+// PostgresAttemptStore menyimpan hitungan di tabel login_attempts,
+// sehingga batas berlaku sama di semua replika.
+type PostgresAttemptStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresAttemptStore membuat store yang memakai tabel login_attempts.
+func NewPostgresAttemptStore(db *sqlx.DB) *PostgresAttemptStore {
+	return &PostgresAttemptStore{db: db}
+}
+
+func (s *PostgresAttemptStore) Get(ctx context.Context, key string, now time.Time) (Attempts, error) {
+	var a []Attempts
+	query := `SELECT failures, last_failure_at FROM login_attempts WHERE key = $1 AND expires_at > $2`
+	if err := s.db.SelectContext(ctx, &a, query, key, now); err != nil || len(a) == 0 {
+		return Attempts{}, err
+	}
+	return a[0], nil
+}
+
+// Fail memakai satu upsert agar kegagalan paralel dari beberapa replika tidak hilang
+func (s *PostgresAttemptStore) Fail(ctx context.Context, key string, now time.Time, window time.Duration) (Attempts, error) {
+	var a Attempts
+	query := `INSERT INTO login_attempts (key, failures, last_failure_at, expires_at) VALUES ($1, 1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN login_attempts.expires_at <= $2 THEN 1 ELSE login_attempts.failures + 1 END,
+			last_failure_at = $2,
+			expires_at = $3
+		RETURNING failures, last_failure_at`
+	err := s.db.GetContext(ctx, &a, query, key, now, now.Add(window))
+	return a, err
+}
+
+func (s *PostgresAttemptStore) Reset(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM login_attempts WHERE key = $1`, key)
+	return err
+}
+
+func (s *PostgresAttemptStore) Sweep(ctx context.Context, now time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM login_attempts WHERE expires_at < $1`, now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
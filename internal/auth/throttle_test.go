@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+
+	"go-flix-api/config"
+	"go-flix-api/internal/problem"
+)
+
+func TestLoginThrottleBackoffAndLockout(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	th := NewLoginThrottle(NewMemoryAttemptStore(), config.LoginThrottleConfig{
+		FreeAttempts: 2, BaseDelay: time.Second, MaxDelay: 4 * time.Second, LockoutThreshold: 6, LockoutDuration: time.Hour,
+	})
+	th.now = func() time.Time { return now }
+
+	wantWait := []time.Duration{0, 0, time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second, time.Hour}
+	for i, want := range wantWait {
+		if wait, err := th.Check(ctx, "User1", "10.0.0.1"); err != nil || wait != want {
+			t.Fatalf("after %d failures: expected wait %v, got %v err=%v", i, want, wait, err)
+		}
+		if i == len(wantWait)-1 {
+			break
+		}
+		result, err := th.Fail(ctx, "user1", "10.0.0.1")
+		if err != nil {
+			t.Fatalf("Fail: %v", err)
+		}
+		if result.Locked != (i+1 == 6) {
+			t.Fatalf("after %d failures: unexpected locked=%v", i+1, result.Locked)
+		}
+	}
+
+	// Jeda dihitung dari kegagalan terakhir
+	now = now.Add(30 * time.Minute)
+	if wait, _ := th.Check(ctx, "user1", "10.0.0.2"); wait != 30*time.Minute {
+		t.Fatalf("expected 30m left of the lockout, got %v", wait)
+	}
+	// IP yang sama untuk username lain hanya kena batas per IP (50)
+	if wait, _ := th.Check(ctx, "user2", "10.0.0.1"); wait != 0 {
+		t.Fatalf("expected other username from the same IP to pass, got %v", wait)
+	}
+	// Setelah penguncian berakhir hitungan dilupakan
+	now = now.Add(31 * time.Minute)
+	if result, _ := th.Fail(ctx, "user1", "10.0.0.3"); result.UserFailures != 1 {
+		t.Fatalf("expected failures to restart after lockout, got %d", result.UserFailures)
+	}
+}
+
+func TestLoginThrottleSuccessResetsUsernameOnly(t *testing.T) {
+	ctx := context.Background()
+	th := NewLoginThrottle(NewMemoryAttemptStore(), config.LoginThrottleConfig{FreeAttempts: 1, IPLockoutThreshold: 100})
+	th.Fail(ctx, "user1", "10.0.0.1")
+	th.Fail(ctx, "user1", "10.0.0.1")
+	if err := th.Succeed(ctx, "USER1"); err != nil {
+		t.Fatalf("Succeed: %v", err)
+	}
+	result, _ := th.Fail(ctx, "user1", "10.0.0.1")
+	if result.UserFailures != 1 || result.IPFailures != 3 {
+		t.Fatalf("expected username count reset but IP count kept, got %+v", result)
+	}
+}
+
+func TestLoginReturns429WithRetryAfter(t *testing.T) {
+	s := newTestService()
+	s.throttle = NewLoginThrottle(NewMemoryAttemptStore(), config.LoginThrottleConfig{FreeAttempts: 1, BaseDelay: 90 * time.Second, MaxDelay: time.Hour})
+	h := NewHandler(s)
+	login := func(password string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.Login(rec, httptest.NewRequest(http.MethodPost, "/api/login", strings.NewReader(`{"username":"user1","password":"`+password+`"}`)))
+		return rec
+	}
+
+	if rec := login("wrong"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for first wrong password, got %d", rec.Code)
+	}
+	rec := login("password123")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "90" {
+		t.Fatalf("expected 429 with Retry-After 90 even for the right password, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if !strings.Contains(rec.Body.String(), problem.CodeLoginThrottled) {
+		t.Fatalf("expected %s code, got %s", problem.CodeLoginThrottled, rec.Body.String())
+	}
+}
+
+func TestPostgresAttemptStore(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+	store := NewPostgresAttemptStore(sqlx.NewDb(db, "sqlmock"))
+	ctx := context.Background()
+	now := time.Now()
+
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO login_attempts")).
+		WithArgs("user:user1", now, now.Add(time.Hour)).
+		WillReturnRows(sqlmock.NewRows([]string{"failures", "last_failure_at"}).AddRow(4, now))
+	a, err := store.Fail(ctx, "user:user1", now, time.Hour)
+	if err != nil || a.Failures != 4 {
+		t.Fatalf("expected 4 failures, got %+v err=%v", a, err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM login_attempts WHERE key = $1 AND expires_at > $2")).
+		WithArgs("ip:10.0.0.1", now).
+		WillReturnRows(sqlmock.NewRows([]string{"failures", "last_failure_at"}))
+	if a, err := store.Get(ctx, "ip:10.0.0.1", now); err != nil || a.Failures != 0 {
+		t.Fatalf("expected no failures, got %+v err=%v", a, err)
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM login_attempts WHERE expires_at < $1")).
+		WithArgs(now).WillReturnResult(sqlmock.NewResult(0, 2))
+	if n, err := store.Sweep(ctx, now); err != nil || n != 2 {
+		t.Fatalf("expected 2 swept, got %d err=%v", n, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
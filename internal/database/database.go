@@ -0,0 +1,133 @@
+// Package database opens the PostgreSQL connection pool described by config.DatabaseConfig.
+package database
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-flix-api/config"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Jeda percobaan ulang koneksi saat start, berlipat dua sampai maxBackoff
+var (
+	initialBackoff = 500 * time.Millisecond
+	maxBackoff     = 10 * time.Second
+)
+
+// DSN builds a lib/pq key/value connection string from cfg.
+// url, bila diisi, menggantikan host/port/user/password/dbname; sslmode dan file TLS
+// dari config menimpa nilai yang ada di url.
+func DSN(cfg config.DatabaseConfig) (string, error) {
+	var parts []string
+	add := func(key, value string) {
+		if value != "" {
+			parts = append(parts, key+"="+quote(value))
+		}
+	}
+	if cfg.URL != "" {
+		base, err := pq.ParseURL(cfg.URL)
+		if err != nil {
+			// Error dari ParseURL bisa mengutip url beserta password
+			return "", fmt.Errorf("database.url is not a valid postgres URL")
+		}
+		parts = append(parts, base)
+	} else {
+		add("host", cfg.Host)
+		add("port", cfg.Port)
+		add("user", cfg.User)
+		add("password", cfg.Password)
+		add("dbname", cfg.DBName)
+		if cfg.SSLMode == "" {
+			add("sslmode", "disable")
+		}
+	}
+	add("sslmode", cfg.SSLMode)
+	add("sslrootcert", cfg.SSLCAFile)
+	add("sslcert", cfg.SSLCertFile)
+	add("sslkey", cfg.SSLKeyFile)
+	if cfg.StatementTimeout > 0 {
+		// Dikirim sebagai parameter startup, berlaku untuk setiap koneksi di pool
+		add("statement_timeout", strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10))
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// quote escapes a value for a key/value connection string
+func quote(v string) string {
+	if v != "" && !strings.ContainsAny(v, ` '\`) {
+		return v
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
+}
+
+var passwordParam = regexp.MustCompile(`password=('(?:[^'\\]|\\.)*'|\S*)`)
+
+// Redact hides the password in a key/value or URL connection string so it can be logged
+func Redact(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" && u.Host != "" {
+		dsn = u.Redacted()
+	}
+	return passwordParam.ReplaceAllString(dsn, "password=xxxxx")
+}
+
+// Connect opens the pool, applies the pool limits and waits until the database answers.
+// Koneksi dicoba ulang dengan backoff sampai cfg.ConnectTimeout habis, misalnya saat
+// aplikasi dan PostgreSQL di-start bersamaan.
+func Connect(ctx context.Context, cfg config.DatabaseConfig) (*sqlx.DB, error) {
+	dsn, err := DSN(cfg)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sqlx.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	if cfg.ConnectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.ConnectTimeout)
+		defer cancel()
+	}
+	if err := ping(ctx, db, Redact(dsn)); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+type pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// ping retries db.PingContext with exponential backoff until it succeeds or ctx is done
+func ping(ctx context.Context, db pinger, redacted string) error {
+	backoff := initialBackoff
+	for attempt := 1; ; attempt++ {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("database not reachable after %d attempts: %w", attempt, err)
+		}
+		slog.Warn("Koneksi database gagal, mencoba lagi", "attempt", attempt, "retry_in", backoff, "dsn", redacted, "error", err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("database not reachable after %d attempts: %w", attempt, err)
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go-flix-api/config"
+)
+
+func TestDSN(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.DatabaseConfig
+		want string
+	}{
+		{
+			name: "fields keep sslmode=disable by default",
+			cfg:  config.DatabaseConfig{Host: "localhost", Port: "5432", User: "postgres", Password: "it's secret", DBName: "go_flix_db"},
+			want: `host=localhost port=5432 user=postgres password='it\'s secret' dbname=go_flix_db sslmode=disable`,
+		},
+		{
+			name: "tls files and statement timeout",
+			cfg: config.DatabaseConfig{
+				Host: "db", User: "app", DBName: "flix", SSLMode: "verify-full",
+				SSLCAFile: "/etc/ssl/ca.pem", SSLCertFile: "/etc/ssl/client.pem", SSLKeyFile: "/etc/ssl/client.key",
+				StatementTimeout: 5 * time.Second,
+			},
+			want: "host=db user=app dbname=flix sslmode=verify-full sslrootcert=/etc/ssl/ca.pem sslcert=/etc/ssl/client.pem sslkey=/etc/ssl/client.key statement_timeout=5000",
+		},
+		{
+			name: "url with sslmode override",
+			cfg:  config.DatabaseConfig{URL: "postgres://app:pw@db.internal:6543/flix?sslmode=require", SSLMode: "verify-full"},
+			want: "dbname='flix' host='db.internal' password='pw' port='6543' sslmode='require' user='app' sslmode=verify-full",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DSN(tt.cfg)
+			if err != nil {
+				t.Fatalf("DSN: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+
+	_, err := DSN(config.DatabaseConfig{URL: "mysql://app:hunter2@db/flix"})
+	if err == nil || strings.Contains(err.Error(), "hunter2") {
+		t.Fatalf("expected error without the password, got %v", err)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	for in, want := range map[string]string{
+		`host=db user=app password='it\'s secret' dbname=flix`: `host=db user=app password=xxxxx dbname=flix`,
+		"host=db password=hunter2 sslmode=disable":             "host=db password=xxxxx sslmode=disable",
+		"postgres://app:hunter2@db:5432/flix?sslmode=require":  "postgres://app:xxxxx@db:5432/flix?sslmode=require",
+	} {
+		if got := Redact(in); got != want {
+			t.Errorf("Redact(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+type flakyDB struct{ failures, calls int }
+
+func (f *flakyDB) PingContext(ctx context.Context) error {
+	f.calls++
+	if f.calls <= f.failures {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestPingRetriesWithBackoff(t *testing.T) {
+	defer func(i, m time.Duration) { initialBackoff, maxBackoff = i, m }(initialBackoff, maxBackoff)
+	initialBackoff, maxBackoff = time.Millisecond, 2*time.Millisecond
+
+	db := &flakyDB{failures: 3}
+	if err := ping(context.Background(), db, ""); err != nil || db.calls != 4 {
+		t.Fatalf("expected success on 4th attempt, got calls=%d err=%v", db.calls, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := ping(ctx, &flakyDB{failures: 1 << 30}, ""); err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Fatalf("expected last error after deadline, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+// Package health serves the liveness and readiness probes.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// pingTimeout membatasi cek database agar probe tidak ikut menggantung saat database lambat
+const pingTimeout = 2 * time.Second
+
+// Pinger is satisfied by *sqlx.DB and *sql.DB
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// Status is the body of both probes
+type Status struct {
+	Status string `json:"status" example:"ok"` // ok, ready, draining atau unavailable
+}
+
+// Checker answers /health and /ready. Readiness fails once SetDraining is called,
+// so the load balancer stops sending traffic before the server shuts down.
+type Checker struct {
+	db       Pinger
+	draining atomic.Bool
+}
+
+// NewChecker returns a checker that reports ready while db answers
+func NewChecker(db Pinger) *Checker {
+	return &Checker{db: db}
+}
+
+// SetDraining makes every following readiness check fail
+func (c *Checker) SetDraining() {
+	c.draining.Store(true)
+}
+
+// @Summary Liveness probe
+// @Description Always 200 while the process is serving requests.
+// @Tags system
+// @Produce json
+// @Success 200 {object} health.Status
+// @Router /health [get]
+// Live menangani GET /health.
+func (c *Checker) Live(w http.ResponseWriter, r *http.Request) {
+	writeStatus(w, http.StatusOK, "ok")
+}
+
+// @Summary Readiness probe
+// @Description 200 when the database answers; 503 while the server is shutting down or the database is unreachable.
+// @Tags system
+// @Produce json
+// @Success 200 {object} health.Status
+// @Failure 503 {object} health.Status
+// @Router /ready [get]
+// Ready menangani GET /ready.
+func (c *Checker) Ready(w http.ResponseWriter, r *http.Request) {
+	if c.draining.Load() {
+		writeStatus(w, http.StatusServiceUnavailable, "draining")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), pingTimeout)
+	defer cancel()
+	if err := c.db.PingContext(ctx); err != nil {
+		slog.Warn("Readiness gagal: database tidak merespons", "error", err)
+		writeStatus(w, http.StatusServiceUnavailable, "unavailable")
+		return
+	}
+	writeStatus(w, http.StatusOK, "ready")
+}
+
+func writeStatus(w http.ResponseWriter, code int, status string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(Status{Status: status})
+}
@@ -0,0 +1,41 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type fakeDB struct{ err error }
+
+func (f fakeDB) PingContext(ctx context.Context) error { return f.err }
+
+func probe(h http.HandlerFunc) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	return rec
+}
+
+func TestReadiness(t *testing.T) {
+	c := NewChecker(fakeDB{})
+	if rec := probe(c.Ready); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"ready"`) {
+		t.Fatalf("expected ready, got %d %s", rec.Code, rec.Body)
+	}
+
+	c.SetDraining()
+	if rec := probe(c.Ready); rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), `"draining"`) {
+		t.Fatalf("expected draining, got %d %s", rec.Code, rec.Body)
+	}
+	// Liveness tetap sehat selama drain agar proses tidak di-restart di tengah shutdown
+	if rec := probe(c.Live); rec.Code != http.StatusOK {
+		t.Fatalf("expected live during drain, got %d", rec.Code)
+	}
+
+	down := NewChecker(fakeDB{err: errors.New("connection refused")})
+	if rec := probe(down.Ready); rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), `"unavailable"`) {
+		t.Fatalf("expected unavailable, got %d %s", rec.Code, rec.Body)
+	}
+}
@@ -1,47 +1,57 @@
 package middleware
 
 import (
-	"context"
 	"net/http"
 	"strings"
 
-	"github.com/golang-jwt/jwt/v5"
+	"go-flix-api/internal/problem"
+	"go-flix-api/internal/rbac"
 )
 
-type JWTClaims struct {
-	Username string `json:"username"`
-	jwt.RegisteredClaims
+// RequireAuth menolak request anonim dengan 401. Dipakai di belakang Authenticator.Middleware.
+func RequireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if PrincipalFromContext(r.Context()).Anonymous() {
+			problem.Error(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "Authentication required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
 }
 
-type DenylistChecker func(jti string) bool
-
-// AuthMiddleware memproteksi endpoint hanya untuk user login
-// Param: secret JWT, fungsi cek denylist
-func AuthMiddleware(secret string, isTokenRevoked DenylistChecker) func(http.Handler) http.Handler {
+// RequirePermission membatasi endpoint untuk principal yang memiliki permission p (lewat role atau scope API key).
+// Harus dipasang setelah Authenticator.Middleware agar principal sudah ada di context.
+// Request anonim dinilai dengan rbac.RoleAnonymous dan mendapat 401 (bukan 403) jika ditolak.
+func RequirePermission(p rbac.Permission) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			authHeader := r.Header.Get("Authorization")
-			if !strings.HasPrefix(authHeader, "Bearer ") {
-				http.Error(w, "Missing or invalid Authorization header", http.StatusUnauthorized)
+			principal := PrincipalFromContext(r.Context())
+			if principal.Has(p) {
+				next.ServeHTTP(w, r)
 				return
 			}
-			tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-			token, err := jwt.ParseWithClaims(tokenStr, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-				return []byte(secret), nil
-			})
-			if err != nil || !token.Valid {
-				http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+			if principal.Anonymous() {
+				problem.Error(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "Authentication required")
 				return
 			}
-			claims, ok := token.Claims.(*JWTClaims)
-			if !ok || (isTokenRevoked != nil && isTokenRevoked(claims.ID)) {
-				http.Error(w, "Token revoked", http.StatusUnauthorized)
-				return
+			problem.Forbidden(w, r, string(p))
+		})
+	}
+}
+
+// RequireRole membatasi endpoint untuk role tertentu saja.
+// Utamakan RequirePermission; RequireRole untuk kasus yang memang terikat ke role, bukan ke kemampuan.
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role := PrincipalFromContext(r.Context()).Role
+			for _, allowed := range roles {
+				if role == allowed {
+					next.ServeHTTP(w, r)
+					return
+				}
 			}
-			// Inject username ke context/header
-			r = r.WithContext(context.WithValue(r.Context(), "username", claims.Username))
-			r.Header.Set("X-Username", claims.Username)
-			next.ServeHTTP(w, r)
+			problem.Error(w, r, http.StatusForbidden, problem.CodeForbidden, "Requires role: "+strings.Join(roles, ", "))
 		})
 	}
 }
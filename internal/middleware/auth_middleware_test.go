@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-flix-api/internal/problem"
+	"go-flix-api/internal/rbac"
+)
+
+func withRole(r *http.Request, role string) *http.Request {
+	return r.WithContext(WithPrincipal(r.Context(), Principal{Username: "someone", Role: role}))
+}
+
+func TestRequirePermission(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	h := RequirePermission(rbac.MoviesWrite)(ok)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, withRole(httptest.NewRequest(http.MethodPost, "/api/movies", nil), rbac.RoleEditor))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected editor allowed, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, withRole(httptest.NewRequest(http.MethodPost, "/api/movies", nil), rbac.RoleViewer))
+	var p problem.Problem
+	if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+		t.Fatalf("decode problem: %v", err)
+	}
+	if rec.Code != http.StatusForbidden || p.MissingPermission != string(rbac.MoviesWrite) {
+		t.Fatalf("expected 403 naming movies:write, got %d %+v", rec.Code, p)
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	h := RequireRole(rbac.RoleAdmin)(ok)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, withRole(httptest.NewRequest(http.MethodGet, "/", nil), rbac.RoleAdmin))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected admin allowed, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 without role, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,253 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"go-flix-api/internal/problem"
+	"go-flix-api/internal/rbac"
+	"go-flix-api/internal/signing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Cara principal diautentikasi, tersimpan di Principal.Method
+const (
+	MethodBearer = "bearer"
+	MethodCookie = "cookie"
+	MethodAPIKey = "api_key"
+)
+
+// Error yang dikembalikan Strategy
+var (
+	// ErrNoCredentials: request tidak membawa kredensial untuk strategy ini, coba strategy berikutnya
+	ErrNoCredentials = errors.New("no credentials")
+	// ErrInvalidCredentials: kredensial ada tetapi tidak valid (rusak, kedaluwarsa, audience lain, ...)
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrRevokedCredentials: kredensial valid tetapi sudah dicabut (logout)
+	ErrRevokedCredentials = errors.New("credentials revoked")
+)
+
+// Principal adalah pemanggil yang sudah diautentikasi
+type Principal struct {
+	Username  string
+	Role      string
+	Scopes    []rbac.Permission // bila diisi (API key), menggantikan permission dari Role
+	Method    string            // MethodBearer, MethodCookie, MethodAPIKey
+	TokenID   string            // jti access token, kosong untuk kredensial lain
+	SessionID string            // family refresh token, kosong untuk kredensial lain
+	ExpiresAt time.Time         // kapan kredensial berhenti berlaku, zero jika tidak kedaluwarsa
+}
+
+// Anonymous reports whether the request came in without credentials
+func (p Principal) Anonymous() bool {
+	return p.Username == ""
+}
+
+// Has reports whether the principal may use permission p: from its scopes
+// when it has any (API keys), otherwise from its role
+func (p Principal) Has(perm rbac.Permission) bool {
+	if p.Scopes != nil {
+		return slices.Contains(p.Scopes, perm)
+	}
+	return rbac.Has(p.Role, perm)
+}
+
+type principalKey struct{}
+
+// WithPrincipal menyimpan principal di context. Dipakai oleh Authenticator dan di test.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext mengembalikan principal request, atau principal anonim
+// (role rbac.RoleAnonymous) bila request tidak diautentikasi
+func PrincipalFromContext(ctx context.Context) Principal {
+	if p, ok := ctx.Value(principalKey{}).(Principal); ok {
+		return p
+	}
+	return Principal{Role: rbac.RoleAnonymous}
+}
+
+// Strategy mengautentikasi satu jenis kredensial
+type Strategy interface {
+	// Authenticate mengembalikan ErrNoCredentials jika request tidak membawa kredensial jenis ini,
+	// dan error yang membungkus ErrInvalidCredentials/ErrRevokedCredentials jika ditolak
+	Authenticate(r *http.Request) (*Principal, error)
+	// Header adalah header request tempat kredensial dibaca, untuk Vary
+	Header() string
+}
+
+// Authenticator mencoba setiap strategy berurutan. Strategy pertama yang menemukan
+// kredensial menentukan hasilnya; kredensial yang tidak valid tidak jatuh ke strategy berikutnya.
+type Authenticator struct {
+	strategies []Strategy
+}
+
+// NewAuthenticator membuat Authenticator dengan urutan strategy yang diberikan
+func NewAuthenticator(strategies ...Strategy) *Authenticator {
+	return &Authenticator{strategies: strategies}
+}
+
+// Authenticate mengembalikan principal anonim jika tidak ada strategy yang menemukan kredensial
+func (a *Authenticator) Authenticate(r *http.Request) (Principal, error) {
+	for _, s := range a.strategies {
+		p, err := s.Authenticate(r)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		if err != nil {
+			return Principal{}, err
+		}
+		return *p, nil
+	}
+	return PrincipalFromContext(r.Context()), nil
+}
+
+// Middleware menyimpan principal di context. Request tanpa kredensial diteruskan sebagai anonim,
+// kredensial yang dikirim tetap harus valid. Pasang RequireAuth atau RequirePermission
+// pada rute yang tidak boleh diakses anonim.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Response berbeda untuk anonim dan user login, jangan di-cache bersama
+		for _, s := range a.strategies {
+			w.Header().Add("Vary", s.Header())
+		}
+		p, err := a.Authenticate(r)
+		switch {
+		case errors.Is(err, ErrRevokedCredentials):
+			problem.Error(w, r, http.StatusUnauthorized, problem.CodeTokenRevoked, "Token revoked")
+			return
+		case errors.Is(err, ErrInvalidCredentials):
+			// Alasan penolakan (audience salah, alg tidak diizinkan, kedaluwarsa, ...) hanya masuk log
+			slog.Warn("Kredensial ditolak",
+				"reason", err,
+				"method", r.Method,
+				"path", r.URL.Path,
+				"request_id", r.Header.Get(problem.RequestIDHeader))
+			problem.Error(w, r, http.StatusUnauthorized, problem.CodeInvalidToken, "Invalid or expired token")
+			return
+		case err != nil:
+			// Fail closed: jika kredensial tidak bisa dicek, request tidak dipercaya
+			problem.Internal(w, r, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+	})
+}
+
+// DenylistChecker memeriksa apakah JTI token sudah di-revoke (logout).
+// auth.Denylist (in-memory maupun PostgreSQL) memenuhi interface ini.
+type DenylistChecker interface {
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+// TokenCutoff mengembalikan batas waktu access token milik username: token dengan iat
+// sebelum batas ini ditolak. Batas diperbarui saat password atau role user berubah.
+// Waktu nol berarti tidak ada batas; akun yang sudah dihapus mengembalikan error
+// yang membungkus ErrRevokedCredentials. Dipenuhi oleh user.Service.
+type TokenCutoff interface {
+	TokensValidAfter(ctx context.Context, username string) (time.Time, error)
+}
+
+// TokenParser memverifikasi JWT ke dalam claims.
+// Dipenuhi oleh *signing.KeySet dan *signing.Keyring (kunci yang bisa di-reload).
+type TokenParser interface {
+	Parse(tokenStr string, claims jwt.Claims) (*jwt.Token, error)
+}
+
+// jwtStrategy memverifikasi access token dari header Authorization atau dari cookie
+type jwtStrategy struct {
+	keys     TokenParser
+	denylist DenylistChecker
+	cutoff   TokenCutoff
+	method   string
+	cookie   string
+}
+
+// BearerJWT membaca access token dari header "Authorization: Bearer <token>".
+// Kunci dipilih lewat header kid; denylist boleh nil untuk menonaktifkan pengecekan logout,
+// cutoff boleh nil untuk menonaktifkan pengecekan perubahan password/role.
+func BearerJWT(keys TokenParser, denylist DenylistChecker, cutoff TokenCutoff) Strategy {
+	return &jwtStrategy{keys: keys, denylist: denylist, cutoff: cutoff, method: MethodBearer}
+}
+
+// SessionCookie membaca access token yang sama dari cookie bernama name (untuk browser).
+// Cookie di-set oleh login dengan SameSite=Strict sehingga tidak ikut terkirim dari situs lain.
+func SessionCookie(name string, keys TokenParser, denylist DenylistChecker, cutoff TokenCutoff) Strategy {
+	return &jwtStrategy{keys: keys, denylist: denylist, cutoff: cutoff, method: MethodCookie, cookie: name}
+}
+
+func (s *jwtStrategy) Header() string {
+	if s.method == MethodCookie {
+		return "Cookie"
+	}
+	return "Authorization"
+}
+
+func (s *jwtStrategy) token(r *http.Request) (string, error) {
+	if s.method == MethodCookie {
+		c, err := r.Cookie(s.cookie)
+		if err != nil || c.Value == "" {
+			return "", ErrNoCredentials
+		}
+		return c.Value, nil
+	}
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return "", ErrNoCredentials
+	}
+	if !strings.HasPrefix(header, "Bearer ") {
+		return "", fmt.Errorf("%w: Authorization header is not a Bearer token", ErrInvalidCredentials)
+	}
+	return strings.TrimPrefix(header, "Bearer "), nil
+}
+
+func (s *jwtStrategy) Authenticate(r *http.Request) (*Principal, error) {
+	tokenStr, err := s.token(r)
+	if err != nil {
+		return nil, err
+	}
+	claims := &signing.Claims{}
+	token, err := s.keys.Parse(tokenStr, claims)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	if !token.Valid {
+		return nil, ErrInvalidCredentials
+	}
+	if s.denylist != nil {
+		revoked, err := s.denylist.IsRevoked(r.Context(), claims.ID)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, ErrRevokedCredentials
+		}
+	}
+	if s.cutoff != nil {
+		after, err := s.cutoff.TokensValidAfter(r.Context(), claims.Username)
+		if err != nil {
+			return nil, err
+		}
+		// iat hanya presisi detik, jadi batas juga dibulatkan ke bawah: login ulang
+		// di detik yang sama dengan perubahan tetap diterima
+		if claims.IssuedAt == nil || claims.IssuedAt.Time.Before(after.Truncate(time.Second)) {
+			return nil, fmt.Errorf("%w: token issued before the account's password or role changed", ErrRevokedCredentials)
+		}
+	}
+	return &Principal{
+		Username:  claims.Username,
+		Role:      claims.Role,
+		Method:    s.method,
+		TokenID:   claims.ID,
+		SessionID: claims.SessionID,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}, nil
+}
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ClientIPResolver menentukan IP client asli. X-Forwarded-For hanya dipercaya bila
+// koneksi datang dari proxy tepercaya; header dari client langsung diabaikan.
+type ClientIPResolver struct {
+	trusted []netip.Prefix
+}
+
+// NewClientIPResolver parses trustedProxies, each a CIDR ("10.0.0.0/8") or a single IP
+func NewClientIPResolver(trustedProxies []string) (*ClientIPResolver, error) {
+	c := &ClientIPResolver{}
+	for _, s := range trustedProxies {
+		if prefix, err := netip.ParsePrefix(s); err == nil {
+			c.trusted = append(c.trusted, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q is neither an IP nor a CIDR", s)
+		}
+		c.trusted = append(c.trusted, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+	return c, nil
+}
+
+func (c *ClientIPResolver) isTrusted(addr netip.Addr) bool {
+	for _, p := range c.trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve returns the client IP of r. X-Forwarded-For is read from right to left,
+// skipping trusted proxies; the first untrusted address is the client.
+func (c *ClientIPResolver) Resolve(r *http.Request) string {
+	remote := remoteAddr(r)
+	addr, err := netip.ParseAddr(remote)
+	if err != nil || !c.isTrusted(addr.Unmap()) {
+		return remote
+	}
+	var hops []string
+	for _, h := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(h, ",")...)
+	}
+	client := addr.Unmap()
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// Entri rusak: berhenti di hop terakhir yang masih valid
+			break
+		}
+		client = hop.Unmap()
+		if !c.isTrusted(client) {
+			break
+		}
+	}
+	return client.String()
+}
+
+type clientIPKey struct{}
+
+// Middleware menyimpan IP client di context untuk ClientIP
+func (c *ClientIPResolver) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), clientIPKey{}, c.Resolve(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// ClientIP mengembalikan IP client yang ditentukan ClientIPResolver.Middleware,
+// atau alamat peer koneksi bila middleware tersebut tidak dipasang
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	return remoteAddr(r)
+}
+
+func remoteAddr(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-flix-api/internal/problem"
+	"go-flix-api/internal/ratelimit"
+)
+
+// RateLimit membatasi request per principal dengan token bucket: per user login,
+// per API key, atau per IP client untuk request anonim. Setiap group punya bucket sendiri.
+// Harus dipasang setelah Authenticator.Middleware agar principal sudah ada di context.
+// Bila limiter gagal (misalnya database tidak bisa dihubungi), request tetap diteruskan.
+func RateLimit(limiter ratelimit.Limiter, group string, rule ratelimit.Rule) func(http.Handler) http.Handler {
+	return rateLimit(limiter, group, rule, func(r *http.Request) string {
+		return group + ":" + rateLimitKey(r)
+	})
+}
+
+// RateLimitClientIP membatasi request per IP client, apa pun kredensialnya.
+// Dipasang sebelum Authenticator.Middleware agar request dengan kredensial salah
+// (mis. menebak API key) tetap terhitung dan ditolak sebelum kredensialnya dicek.
+// Bucket-nya terpisah dari bucket RateLimit untuk group yang sama.
+func RateLimitClientIP(limiter ratelimit.Limiter, group string, rule ratelimit.Rule) func(http.Handler) http.Handler {
+	return rateLimit(limiter, group, rule, func(r *http.Request) string {
+		return group + ":client:" + ClientIP(r)
+	})
+}
+
+func rateLimit(limiter ratelimit.Limiter, group string, rule ratelimit.Rule, keyOf func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := keyOf(r)
+			res, err := limiter.Allow(r.Context(), key, rule, time.Now())
+			if err != nil {
+				slog.Error("Rate limiter gagal, request diteruskan",
+					"error", err,
+					"group", group,
+					"request_id", r.Header.Get(problem.RequestIDHeader))
+				next.ServeHTTP(w, r)
+				return
+			}
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			h.Set("RateLimit-Reset", ceilSeconds(res.Reset))
+			if !res.Allowed {
+				h.Set("Retry-After", ceilSeconds(res.RetryAfter))
+				problem.Error(w, r, http.StatusTooManyRequests, problem.CodeRateLimited, "Rate limit exceeded, try again later")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimitKey identifies the caller: the API key, the username, or the client IP
+func rateLimitKey(r *http.Request) string {
+	p := PrincipalFromContext(r.Context())
+	switch {
+	case p.Method == MethodAPIKey:
+		return p.Username // "apikey:<prefix>"
+	case !p.Anonymous():
+		return "user:" + p.Username
+	default:
+		return "ip:" + ClientIP(r)
+	}
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+
+	"go-flix-api/internal/problem"
+
+	"github.com/google/uuid"
+)
+
+// maxRequestIDLength membatasi request ID dari client agar tidak bisa dipakai untuk log injection
+const maxRequestIDLength = 128
+
+// RequestID memastikan setiap request punya X-Request-ID (dari client atau di-generate)
+// dan mengembalikannya di response, sehingga bisa dicocokkan dengan log dan body error
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(problem.RequestIDHeader)
+		if id == "" || len(id) > maxRequestIDLength || !isPrintableASCII(id) {
+			id = uuid.New().String()
+			r.Header.Set(problem.RequestIDHeader, id)
+		}
+		w.Header().Set(problem.RequestIDHeader, id)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isPrintableASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 || s[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
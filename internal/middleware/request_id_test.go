@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.Header.Get("X-Request-ID")
+	}))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Request-ID", "client-id-1")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if seen != "client-id-1" || w.Header().Get("X-Request-ID") != "client-id-1" {
+		t.Fatalf("expected client request id to be kept, got %q / %q", seen, w.Header().Get("X-Request-ID"))
+	}
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Request-ID", "bad\nid")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if seen == "" || seen == "bad\nid" || w.Header().Get("X-Request-ID") != seen {
+		t.Fatalf("expected generated request id, got %q", seen)
+	}
+}
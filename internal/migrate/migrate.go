@@ -0,0 +1,276 @@
+// Package migrate applies the numbered SQL migrations in database/migrations
+// and records them in the schema_migrations table.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// lockID is the pg_advisory_lock key held while migrating, so replicas
+// starting at the same time with auto_migrate do not run the same migration twice
+const lockID int64 = 0x676f666c6978 // "goflix"
+
+// Migration is one numbered schema change with its rollback
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string // kosong = tidak bisa di-rollback
+}
+
+// Status is a migration and whether it has been applied
+type Status struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time // nil = belum dijalankan
+	Missing   bool       // tercatat di database tetapi file-nya tidak ada di binary ini
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Load reads NNNN_name.up.sql / NNNN_name.down.sql files from fsys, sorted by version.
+// Every version needs an up file; down files are optional.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".sql") {
+			continue
+		}
+		m := fileName.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("%s: expected NNNN_name.up.sql or NNNN_name.down.sql", e.Name())
+		}
+		version, _ := strconv.ParseInt(m[1], 10, 64)
+		data, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		}
+		if mig.Name != m[2] {
+			return nil, fmt.Errorf("version %d is used by both %q and %q", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(data)
+		} else {
+			mig.Down = string(data)
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("version %d (%s) has no up migration", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator runs migrations against one database
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []Migration
+}
+
+// New returns a migrator for migrations, which must be sorted by version (as Load returns them)
+func New(db *sqlx.DB, migrations []Migration) *Migrator {
+	return &Migrator{db: db, migrations: migrations}
+}
+
+// Up applies every pending migration in order, each in its own transaction,
+// and returns the ones applied. It stops at the first failure.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.locked(ctx, func(conn *sqlx.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := done[mig.Version]; ok {
+				continue
+			}
+			err := inTx(ctx, conn, mig.Up,
+				`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, mig.Version, mig.Name)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", mig.Version, mig.Name, err)
+			}
+			applied = append(applied, mig)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down rolls back the last steps applied migrations, newest first, and returns them
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	byVersion := map[int64]Migration{}
+	for _, mig := range m.migrations {
+		byVersion[mig.Version] = mig
+	}
+	var rolledBack []Migration
+	err := m.locked(ctx, func(conn *sqlx.Conn) error {
+		var versions []int64
+		err := conn.SelectContext(ctx, &versions,
+			`SELECT version FROM schema_migrations ORDER BY version DESC LIMIT $1`, steps)
+		if err != nil {
+			return err
+		}
+		for _, v := range versions {
+			mig, ok := byVersion[v]
+			if !ok {
+				return fmt.Errorf("migration %d is applied but not known to this binary", v)
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("migration %d_%s has no down migration", mig.Version, mig.Name)
+			}
+			err := inTx(ctx, conn, mig.Down, `DELETE FROM schema_migrations WHERE version = $1`, mig.Version)
+			if err != nil {
+				return fmt.Errorf("rollback %d_%s: %w", mig.Version, mig.Name, err)
+			}
+			rolledBack = append(rolledBack, mig)
+		}
+		return nil
+	})
+	return rolledBack, err
+}
+
+// Status lists every known migration plus applied versions missing from this binary, by version
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.locked(ctx, func(conn *sqlx.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			s := Status{Version: mig.Version, Name: mig.Name}
+			if row, ok := done[mig.Version]; ok {
+				s.AppliedAt = &row.AppliedAt
+				delete(done, mig.Version)
+			}
+			statuses = append(statuses, s)
+		}
+		for _, row := range done {
+			appliedAt := row.AppliedAt
+			statuses = append(statuses, Status{Version: row.Version, Name: row.Name, AppliedAt: &appliedAt, Missing: true})
+		}
+		return nil
+	})
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, err
+}
+
+// locked runs fn on a single connection holding the migration advisory lock,
+// after making sure schema_migrations exists
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sqlx.Conn) error) error {
+	// Advisory lock milik session, jadi semua statement harus lewat koneksi yang sama
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID)
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name VARCHAR(200) NOT NULL,
+		applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+type appliedRow struct {
+	Version   int64     `db:"version"`
+	Name      string    `db:"name"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+func appliedVersions(ctx context.Context, conn *sqlx.Conn) (map[int64]appliedRow, error) {
+	var rows []appliedRow
+	if err := conn.SelectContext(ctx, &rows, `SELECT version, name, applied_at FROM schema_migrations`); err != nil {
+		return nil, err
+	}
+	done := make(map[int64]appliedRow, len(rows))
+	for _, r := range rows {
+		done[r.Version] = r
+	}
+	return done, nil
+}
+
+// inTx runs script and the bookkeeping statement in one transaction
+func inTx(ctx context.Context, conn *sqlx.Conn, script, record string, args ...any) error {
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Create writes empty up and down files for the next version in dir and returns their paths
+func Create(dir, name string) (up, down string, err error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(name, "_")
+	name = strings.Trim(name, "_")
+	if name == "" {
+		return "", "", errors.New("migration name is required")
+	}
+	existing, err := Load(os.DirFS(dir))
+	if err != nil {
+		return "", "", err
+	}
+	var next int64 = 1
+	if len(existing) > 0 {
+		next = existing[len(existing)-1].Version + 1
+	}
+	base := filepath.Join(dir, fmt.Sprintf("%04d_%s", next, name))
+	up, down = base+".up.sql", base+".down.sql"
+	for path, comment := range map[string]string{up: "-- Perubahan skema\n", down: "-- Kebalikan dari " + filepath.Base(up) + "\n"} {
+		// O_EXCL: jangan menimpa file yang ternyata sudah ada
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return "", "", err
+		}
+		_, err = f.WriteString(comment)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return "", "", err
+		}
+	}
+	return up, down, nil
+}
@@ -0,0 +1,134 @@
+package migrate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+
+	"go-flix-api/database/migrations"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_add_rating.up.sql":      {Data: []byte("ALTER TABLE movies ADD COLUMN rating INT;")},
+		"0002_add_rating.down.sql":    {Data: []byte("ALTER TABLE movies DROP COLUMN rating;")},
+		"0001_initial_schema.up.sql":  {Data: []byte("CREATE TABLE movies (id UUID);")},
+		"migrations.go":               {Data: []byte("package migrations")},
+		"0010_no_rollback.up.sql":     {Data: []byte("SELECT 1;")},
+		"0001_initial_schema.down.sq": {Data: []byte("ignored")},
+	}
+	got, err := Load(fsys)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	want := []int64{1, 2, 10}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d migrations, got %d", len(want), len(got))
+	}
+	for i, v := range want {
+		if got[i].Version != v {
+			t.Fatalf("migration %d: expected version %d, got %d", i, v, got[i].Version)
+		}
+	}
+	if got[1].Name != "add_rating" || got[1].Down == "" || got[2].Down != "" {
+		t.Fatalf("unexpected migrations %+v", got)
+	}
+
+	for name, bad := range map[string]fstest.MapFS{
+		"down without up":   {"0001_x.down.sql": {Data: []byte("x")}},
+		"duplicate version": {"0001_a.up.sql": {Data: []byte("x")}, "0001_b.up.sql": {Data: []byte("x")}},
+		"bad name":          {"add_rating.up.sql": {Data: []byte("x")}},
+	} {
+		if _, err := Load(bad); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestEmbeddedMigrationsLoad(t *testing.T) {
+	got, err := Load(migrations.FS)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(got) == 0 || got[0].Version != 1 || got[0].Down == "" {
+		t.Fatalf("expected 0001 with a down migration first, got %+v", got)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "0001_initial_schema.up.sql"), []byte("SELECT 1;"), 0o644)
+
+	up, down, err := Create(dir, "Add movie rating")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if filepath.Base(up) != "0002_add_movie_rating.up.sql" || filepath.Base(down) != "0002_add_movie_rating.down.sql" {
+		t.Fatalf("unexpected files %s, %s", up, down)
+	}
+	if _, _, err := Create(dir, "  "); err == nil {
+		t.Fatal("expected error for empty name")
+	}
+}
+
+func TestUpAppliesPendingUnderLock(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	m := New(sqlx.NewDb(sqlDB, "postgres"), []Migration{
+		{Version: 1, Name: "initial_schema", Up: "CREATE TABLE movies (id UUID)"},
+		{Version: 2, Name: "add_rating", Up: "ALTER TABLE movies ADD COLUMN rating INT"},
+	})
+
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_lock($1)")).WithArgs(lockID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE IF NOT EXISTS schema_migrations")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT version, name, applied_at FROM schema_migrations")).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "name", "applied_at"}).AddRow(1, "initial_schema", time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE movies ADD COLUMN rating INT")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO schema_migrations")).WithArgs(int64(2), "add_rating").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).WithArgs(lockID).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	applied, err := m.Up(context.Background())
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if len(applied) != 1 || applied[0].Version != 2 {
+		t.Fatalf("expected only version 2 applied, got %+v", applied)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestDownRequiresDownMigration(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	m := New(sqlx.NewDb(sqlDB, "postgres"), []Migration{{Version: 1, Name: "initial_schema", Up: "SELECT 1"}})
+
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_lock($1)")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE IF NOT EXISTS schema_migrations")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT version FROM schema_migrations ORDER BY version DESC LIMIT $1")).
+		WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(1))
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if _, err := m.Down(context.Background(), 1); err == nil {
+		t.Fatal("expected error for migration without down file")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
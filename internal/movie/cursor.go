@@ -0,0 +1,86 @@
+package movie
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"go-flix-api/models"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidCursor is returned when a cursor is malformed or its signature does not match
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrExpiredCursor is returned when a correctly signed cursor is past its expiry
+	ErrExpiredCursor = errors.New("cursor expired")
+)
+
+// cursorPayload is the signed content of a keyset pagination cursor
+type cursorPayload struct {
+	CreatedAt time.Time `json:"c"`
+	ID        uuid.UUID `json:"i"`
+	ExpiresAt int64     `json:"e"`
+}
+
+// CursorCodec encodes and verifies opaque, HMAC-signed keyset pagination cursors
+type CursorCodec struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+// NewCursorCodec creates a codec signing cursors with secret that stay valid for ttl
+func NewCursorCodec(secret []byte, ttl time.Duration) *CursorCodec {
+	return &CursorCodec{secret: secret, ttl: ttl, now: time.Now}
+}
+
+// Encode returns an opaque cursor pointing just after the given (created_at, id) position
+func (c *CursorCodec) Encode(createdAt time.Time, id uuid.UUID) (string, error) {
+	payload, err := json.Marshal(cursorPayload{
+		CreatedAt: createdAt,
+		ID:        id,
+		ExpiresAt: c.now().Add(c.ttl).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(c.sign(payload)), nil
+}
+
+// Decode verifies a cursor and returns the keyset position it encodes
+func (c *CursorCodec) Decode(cursor string) (*models.MovieCursor, error) {
+	enc := base64.RawURLEncoding
+	rawPayload, rawSig, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	payload, err := enc.DecodeString(rawPayload)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	sig, err := enc.DecodeString(rawSig)
+	if err != nil || !hmac.Equal(sig, c.sign(payload)) {
+		return nil, ErrInvalidCursor
+	}
+	var p cursorPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.now().Unix() > p.ExpiresAt {
+		return nil, ErrExpiredCursor
+	}
+	return &models.MovieCursor{CreatedAt: p.CreatedAt, ID: p.ID}, nil
+}
+
+func (c *CursorCodec) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
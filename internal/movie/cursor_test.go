@@ -0,0 +1,47 @@
+package movie
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	c := NewCursorCodec([]byte("test_secret"), time.Hour)
+	createdAt := time.Date(2024, 5, 6, 7, 8, 9, 123456000, time.UTC)
+	id := uuid.New()
+
+	cursor, err := c.Encode(createdAt, id)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	pos, err := c.Decode(cursor)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if !pos.CreatedAt.Equal(createdAt) || pos.ID != id {
+		t.Fatalf("unexpected position: %+v", pos)
+	}
+}
+
+func TestCursorRejectsTamperingAndExpiry(t *testing.T) {
+	c := NewCursorCodec([]byte("test_secret"), time.Hour)
+	cursor, _ := c.Encode(time.Now(), uuid.New())
+
+	other := NewCursorCodec([]byte("other_secret"), time.Hour)
+	if _, err := other.Decode(cursor); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("expected ErrInvalidCursor for foreign signature, got %v", err)
+	}
+	for _, bad := range []string{"", "garbage", cursor[:len(cursor)-2], "x" + cursor} {
+		if _, err := c.Decode(bad); !errors.Is(err, ErrInvalidCursor) {
+			t.Fatalf("expected ErrInvalidCursor for %q, got %v", bad, err)
+		}
+	}
+
+	c.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if _, err := c.Decode(cursor); !errors.Is(err, ErrExpiredCursor) {
+		t.Fatalf("expected ErrExpiredCursor, got %v", err)
+	}
+}
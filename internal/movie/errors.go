@@ -0,0 +1,54 @@
+package movie
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// Domain errors returned by Service. Handlers map them to status codes with errors.Is,
+// so callers never need to compare error strings or know about the database driver.
+var (
+	// ErrNotFound means no active movie has the requested ID
+	ErrNotFound = errors.New("movie not found")
+	// ErrAlreadyDeleted means the movie exists but is already in the trash
+	ErrAlreadyDeleted = errors.New("movie already deleted")
+	// ErrConflict means the write clashes with existing data, e.g. a unique constraint
+	ErrConflict = errors.New("movie conflicts with existing data")
+	// ErrValidation means the request or the data it produced is invalid.
+	// Request validation failures also wrap validation.Errors with the per-field details.
+	ErrValidation = errors.New("invalid movie")
+	// ErrVersionMismatch is returned when the stored version differs from the version the caller expected
+	ErrVersionMismatch = errors.New("version mismatch")
+)
+
+// PostgreSQL error codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	pqUniqueViolation      = "23505"
+	pqNotNullViolation     = "23502"
+	pqCheckViolation       = "23514"
+	pqStringDataTruncation = "22001"
+)
+
+// translateError maps sql.ErrNoRows and pq constraint errors onto the domain errors above.
+// Anything else (connection failures, syntax errors) is returned unchanged.
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case pqUniqueViolation:
+			return fmt.Errorf("%w: %s", ErrConflict, pqErr.Detail)
+		case pqNotNullViolation, pqCheckViolation, pqStringDataTruncation:
+			return fmt.Errorf("%w: %s", ErrValidation, pqErr.Message)
+		}
+	}
+	return err
+}
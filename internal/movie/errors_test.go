@@ -0,0 +1,56 @@
+package movie
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-flix-api/internal/validation"
+
+	"github.com/lib/pq"
+)
+
+func TestTranslateError(t *testing.T) {
+	if translateError(nil) != nil {
+		t.Fatalf("expected nil")
+	}
+	if !errors.Is(translateError(sql.ErrNoRows), ErrNotFound) {
+		t.Fatalf("expected sql.ErrNoRows to become ErrNotFound")
+	}
+	if !errors.Is(translateError(&pq.Error{Code: pqUniqueViolation}), ErrConflict) {
+		t.Fatalf("expected unique violation to become ErrConflict")
+	}
+	if !errors.Is(translateError(&pq.Error{Code: pqStringDataTruncation}), ErrValidation) {
+		t.Fatalf("expected string truncation to become ErrValidation")
+	}
+	other := &pq.Error{Code: "57P01"} // admin_shutdown
+	if got := translateError(other); got != other {
+		t.Fatalf("expected unrelated pq error to pass through, got %v", got)
+	}
+}
+
+func TestWriteErrorStatusCodes(t *testing.T) {
+	cases := []struct {
+		err  error
+		want int
+	}{
+		{fmt.Errorf("%w: %w", ErrValidation, validation.Errors{{Field: "judul"}}), http.StatusUnprocessableEntity},
+		{ErrValidation, http.StatusUnprocessableEntity},
+		{ErrNotFound, http.StatusNotFound},
+		{ErrAlreadyDeleted, http.StatusGone},
+		{ErrVersionMismatch, http.StatusPreconditionFailed},
+		{fmt.Errorf("%w: duplicate", ErrConflict), http.StatusConflict},
+		{ErrExpiredCursor, http.StatusBadRequest},
+		{errors.New("connection refused"), http.StatusInternalServerError},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		writeError(w, httptest.NewRequest("GET", "/api/movies/x", nil), tc.err)
+		if w.Code != tc.want {
+			t.Fatalf("%v: expected %d, got %d", tc.err, tc.want, w.Code)
+		}
+	}
+}
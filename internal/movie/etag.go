@@ -0,0 +1,36 @@
+package movie
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// errBadIfMatch is returned when the If-Match header cannot be mapped onto a movie version
+var errBadIfMatch = errors.New("If-Match must be \"*\" or a single ETag from GET /api/movies/{id}")
+
+// etag returns the strong entity tag for a movie version, e.g. "v3"
+func etag(version int) string {
+	return fmt.Sprintf(`"v%d"`, version)
+}
+
+// ifMatchVersion parses the If-Match header into the expected movie version.
+// It returns nil when the header is absent or "*" (any current version is acceptable).
+func ifMatchVersion(r *http.Request) (*int, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+	// If-Match memakai strong comparison, jadi weak ETag (W/"...") tidak pernah cocok
+	tag, ok := strings.CutPrefix(header, `"v`)
+	if !ok || !strings.HasSuffix(tag, `"`) {
+		return nil, errBadIfMatch
+	}
+	version, err := strconv.Atoi(strings.TrimSuffix(tag, `"`))
+	if err != nil {
+		return nil, errBadIfMatch
+	}
+	return &version, nil
+}
@@ -2,8 +2,16 @@ package movie
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"go-flix-api/internal/middleware"
+	"go-flix-api/internal/problem"
+	"go-flix-api/internal/validation"
 	"go-flix-api/models"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 )
@@ -16,30 +24,212 @@ func NewHandler(service *Service) *Handler {
 	return &Handler{service: service}
 }
 
+// writeError maps service errors onto problem responses with the matching status code
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var verrs validation.Errors
+	switch {
+	case errors.As(err, &verrs):
+		problem.Validation(w, r, verrs)
+	case errors.Is(err, ErrValidation):
+		problem.Error(w, r, http.StatusUnprocessableEntity, problem.CodeValidationFailed, err.Error())
+	case errors.Is(err, ErrNotFound):
+		problem.Error(w, r, http.StatusNotFound, problem.CodeMovieNotFound, "Movie not found")
+	case errors.Is(err, ErrAlreadyDeleted):
+		problem.Error(w, r, http.StatusGone, problem.CodeMovieDeleted, "Movie is already in the trash")
+	case errors.Is(err, ErrVersionMismatch):
+		problem.Error(w, r, http.StatusPreconditionFailed, problem.CodePreconditionFailed, "Movie was modified by someone else, fetch it again and retry")
+	case errors.Is(err, ErrConflict):
+		problem.Error(w, r, http.StatusConflict, problem.CodeConflict, err.Error())
+	case errors.Is(err, ErrInvalidCursor), errors.Is(err, ErrExpiredCursor):
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidCursor, err.Error())
+	default:
+		problem.Internal(w, r, err)
+	}
+}
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// isAnonymous reports whether the request came in without a token (public read access)
+func isAnonymous(r *http.Request) bool {
+	return middleware.PrincipalFromContext(r.Context()).Anonymous()
+}
+
 // @Summary Get all movies
-// @Description Get a list of all movies
+// @Description Get a paginated, filterable and sortable list of movies. Public: without a token the created_by/updated_by fields are omitted.
 // @Tags movies
 // @Produce json
-// @Success 200 {array} models.Movie
+// @Param page query int false "Page number (starts at 1)" default(1)
+// @Param limit query int false "Page size (max 100)" default(20)
+// @Param genre query string false "Filter by genre (case-insensitive exact match)"
+// @Param tahun_rilis_min query int false "Minimum release year (inclusive)"
+// @Param tahun_rilis_max query int false "Maximum release year (inclusive)"
+// @Param sutradara query string false "Filter by director (substring match)"
+// @Param pemeran query string false "Filter by cast member (exact match on one entry)"
+// @Param sort query string false "Sort column" Enums(judul, genre, tahun_rilis, sutradara, created_at, updated_at) default(created_at)
+// @Param order query string false "Sort direction" Enums(asc, desc) default(asc)
+// @Param cursor query string false "Keyset pagination cursor from next_cursor; pass an empty value to start (newest first, cannot be combined with page, sort or order)"
+// @Success 200 {object} models.MovieListResponse
+// @Failure 400 {object} problem.Problem "Invalid query parameter, tampered or expired cursor"
 // @Router /movies [get]
 func (h *Handler) GetAllMovies(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	movies, err := h.service.GetAllMovies(ctx)
+	params, err := parseListParams(r.URL.Query())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeBadRequest, err.Error())
 		return
 	}
+	resp, err := h.service.GetAllMovies(ctx, params)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if params.CursorMode {
+		if resp.NextCursor != "" {
+			next := cursorURL(r.URL, resp.NextCursor)
+			resp.Links.Next = &next
+		}
+	} else {
+		if params.Page*params.Limit < resp.Total {
+			next := pageURL(r.URL, params.Page+1)
+			resp.Links.Next = &next
+		}
+		if params.Page > 1 {
+			prev := pageURL(r.URL, params.Page-1)
+			resp.Links.Prev = &prev
+		}
+	}
+	if isAnonymous(r) {
+		for i := range resp.Data {
+			resp.Data[i].StripAudit()
+		}
+	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(movies)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// parseListParams reads the pagination, filter and sort query parameters, applying defaults
+func parseListParams(q url.Values) (models.MovieListParams, error) {
+	params := models.MovieListParams{
+		Page:      1,
+		Limit:     defaultPageLimit,
+		Genre:     q.Get("genre"),
+		Sutradara: q.Get("sutradara"),
+		Pemeran:   q.Get("pemeran"),
+		Sort:      q.Get("sort"),
+		Order:     strings.ToLower(q.Get("order")),
+
+		CursorMode: q.Has("cursor"),
+		Cursor:     q.Get("cursor"),
+	}
+	var err error
+	if v := q.Get("page"); v != "" {
+		if params.Page, err = strconv.Atoi(v); err != nil || params.Page < 1 {
+			return params, fmt.Errorf("invalid page: must be a positive integer")
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if params.Limit, err = strconv.Atoi(v); err != nil || params.Limit < 1 || params.Limit > maxPageLimit {
+			return params, fmt.Errorf("invalid limit: must be between 1 and %d", maxPageLimit)
+		}
+	}
+	if params.TahunRilisMin, err = parseOptionalInt(q, "tahun_rilis_min"); err != nil {
+		return params, err
+	}
+	if params.TahunRilisMax, err = parseOptionalInt(q, "tahun_rilis_max"); err != nil {
+		return params, err
+	}
+	if params.TahunRilisMin != nil && params.TahunRilisMax != nil && *params.TahunRilisMin > *params.TahunRilisMax {
+		return params, fmt.Errorf("invalid tahun_rilis range: tahun_rilis_min must not be greater than tahun_rilis_max")
+	}
+	if params.Sort != "" && !IsSortable(params.Sort) {
+		return params, fmt.Errorf("invalid sort: %q is not a sortable column", params.Sort)
+	}
+	if params.Order != "" && params.Order != "asc" && params.Order != "desc" {
+		return params, fmt.Errorf("invalid order: must be asc or desc")
+	}
+	if params.CursorMode && (params.Sort != "" || params.Order != "" || q.Has("page")) {
+		return params, fmt.Errorf("page, sort and order cannot be combined with cursor")
+	}
+	return params, nil
+}
+
+func parseOptionalInt(q url.Values, key string) (*int, error) {
+	v := q.Get(key)
+	if v == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: must be an integer", key)
+	}
+	return &n, nil
+}
+
+// cursorURL returns the request URL (path and query) pointing at the next cursor page
+func cursorURL(u *url.URL, cursor string) string {
+	q := u.Query()
+	q.Set("cursor", cursor)
+	return u.Path + "?" + q.Encode()
+}
+
+// pageURL returns the request URL (path and query) pointing at another page
+func pageURL(u *url.URL, page int) string {
+	q := u.Query()
+	q.Set("page", strconv.Itoa(page))
+	return u.Path + "?" + q.Encode()
+}
+
+// @Summary Search movies
+// @Description Full-text search over judul, sutradara and pemeran ranked by relevance, with a trigram fallback for typos. Public: without a token the audit fields are omitted.
+// @Tags movies
+// @Produce json
+// @Param q query string true "Search terms (websearch syntax: quotes, OR, -exclude)"
+// @Param limit query int false "Maximum number of results (max 100)" default(20)
+// @Success 200 {object} models.MovieSearchResponse
+// @Failure 400 {object} problem.Problem
+// @Router /movies/search [get]
+func (h *Handler) SearchMovies(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeBadRequest, "Query parameter q is required")
+		return
+	}
+	limit := defaultPageLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageLimit {
+			problem.Error(w, r, http.StatusBadRequest, problem.CodeBadRequest, fmt.Sprintf("invalid limit: must be between 1 and %d", maxPageLimit))
+			return
+		}
+		limit = n
+	}
+	results, fuzzy, err := h.service.SearchMovies(ctx, q, limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if isAnonymous(r) {
+		for i := range results {
+			results[i].StripAudit()
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.MovieSearchResponse{Data: results, Query: q, Fuzzy: fuzzy})
 }
 
 // @Summary Get movie by ID
-// @Description Get a movie by its ID
+// @Description Get a movie by its ID. Public: without a token the audit fields are omitted.
 // @Tags movies
 // @Produce json
 // @Param id path string true "Movie ID"
 // @Success 200 {object} models.Movie
-// @Failure 404 {object} map[string]string
+// @Header 200 {string} ETag "Current version of the movie, for use in If-Match"
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /movies/{id} [get]
 func (h *Handler) GetMovieByID(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -47,9 +237,13 @@ func (h *Handler) GetMovieByID(w http.ResponseWriter, r *http.Request) {
 	id := vars["id"]
 	movie, err := h.service.GetMovieByID(ctx, id)
 	if err != nil {
-		http.Error(w, "Movie not found", http.StatusNotFound)
+		writeError(w, r, err)
 		return
 	}
+	if isAnonymous(r) {
+		movie.StripAudit()
+	}
+	w.Header().Set("ETag", etag(movie.Version))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(movie)
 }
@@ -61,23 +255,26 @@ func (h *Handler) GetMovieByID(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param movie body models.CreateMovieRequest true "Movie to create"
 // @Success 201 {object} models.Movie
-// @Failure 400 {object} map[string]string
+// @Failure 400 {object} problem.Problem
+// @Failure 403 {object} problem.Problem "Missing movies:write permission"
+// @Failure 409 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
 // @Router /movies [post]
 func (h *Handler) CreateMovie(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	var req models.CreateMovieRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "Invalid JSON format")
 		return
 	}
-	// Ambil username dari header (hasil middleware JWT)
-	username := r.Header.Get("X-Username")
+	// Ambil username dari principal (hasil Authenticator), bukan dari header yang bisa dipalsukan
+	username := middleware.PrincipalFromContext(ctx).Username
 	if username != "" {
 		req.CreatedBy = &username
 	}
 	movie, err := h.service.CreateMovie(ctx, req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -86,15 +283,23 @@ func (h *Handler) CreateMovie(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary Update a movie
-// @Description Update a movie by its ID
+// @Description Update a movie by its ID. Fields left out keep their stored value. Even without If-Match the update
+// @Description fails with 412 if another write changed the movie between reading and writing it, so no change is lost
+// @Description silently; unlike DELETE, which without If-Match always deletes the current version.
 // @Tags movies
 // @Accept json
 // @Produce json
 // @Param id path string true "Movie ID"
+// @Param If-Match header string false "ETag from GET /movies/{id}; the update fails with 412 if the movie changed since"
 // @Param movie body models.UpdateMovieRequest true "Movie fields to update"
 // @Success 200 {object} map[string]string
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
+// @Header 200 {string} ETag "New version of the movie"
+// @Failure 400 {object} problem.Problem
+// @Failure 403 {object} problem.Problem "Missing movies:write permission"
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 412 {object} problem.Problem "Version mismatch"
+// @Failure 422 {object} problem.Problem
 // @Router /movies/{id} [put]
 func (h *Handler) UpdateMovie(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -102,40 +307,140 @@ func (h *Handler) UpdateMovie(w http.ResponseWriter, r *http.Request) {
 	id := vars["id"]
 	var req models.UpdateMovieRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "Invalid JSON format")
 		return
 	}
-	username := r.Header.Get("X-Username")
-	if err := h.service.UpdateMovie(ctx, id, req, username); err != nil {
-		if err.Error() == "no rows updated" {
-			http.Error(w, "Movie not found", http.StatusNotFound)
+	// Tanpa If-Match atau version di body, service tetap menjaga versi yang baru dibaca (bisa 412)
+	expected, err := ifMatchVersion(r)
+	if err != nil {
+		problem.Error(w, r, http.StatusPreconditionFailed, problem.CodePreconditionFailed, err.Error())
+		return
+	}
+	if expected != nil {
+		if req.Version != nil && *req.Version != *expected {
+			problem.Error(w, r, http.StatusBadRequest, problem.CodeBadRequest, "If-Match and version in body disagree")
 			return
 		}
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		req.Version = expected
+	}
+	username := middleware.PrincipalFromContext(ctx).Username
+	movie, err := h.service.UpdateMovie(ctx, id, req, username)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
+	w.Header().Set("ETag", etag(movie.Version))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "movie updated"})
 }
 
 // @Summary Delete a movie
-// @Description Soft delete a movie by its ID
+// @Description Soft delete a movie by its ID. With hard=true the movie is purged permanently instead (requires movies:purge, cannot be undone).
 // @Tags movies
 // @Produce json
 // @Param id path string true "Movie ID"
+// @Param hard query bool false "Permanently delete instead of moving to the trash (requires movies:purge)"
+// @Param If-Match header string false "ETag from GET /movies/{id}; the delete fails with 412 if the movie changed since"
 // @Success 204 {object} nil
-// @Failure 404 {object} map[string]string
+// @Failure 403 {object} problem.Problem "Missing movies:write, or movies:purge with hard=true"
+// @Failure 404 {object} problem.Problem
+// @Failure 410 {object} problem.Problem "Movie is already in the trash"
+// @Failure 412 {object} problem.Problem "Version mismatch"
 // @Router /movies/{id} [delete]
 func (h *Handler) DeleteMovie(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	vars := mux.Vars(r)
 	id := vars["id"]
-	if err := h.service.DeleteMovie(ctx, id); err != nil {
-		if err.Error() == "no rows deleted" || err.Error() == "movie already deleted" {
-			http.Error(w, "Movie not found", http.StatusNotFound)
+	// Tanpa If-Match versi tidak dijaga: tidak ada field yang digabung, jadi tidak ada perubahan yang hilang
+	expected, err := ifMatchVersion(r)
+	if err != nil {
+		problem.Error(w, r, http.StatusPreconditionFailed, problem.CodePreconditionFailed, err.Error())
+		return
+	}
+	username := middleware.PrincipalFromContext(ctx).Username
+	if err := h.service.DeleteMovie(ctx, id, username, expected); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary List deleted movies
+// @Description List soft-deleted movies (trash bin), most recently deleted first. Requires movies:purge (admin).
+// @Tags movies
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number (starts at 1)" default(1)
+// @Param limit query int false "Page size (max 100)" default(20)
+// @Success 200 {object} models.MovieListResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Router /movies/trash [get]
+func (h *Handler) ListTrash(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	q := r.URL.Query()
+	for key := range q {
+		if key != "page" && key != "limit" {
+			problem.Error(w, r, http.StatusBadRequest, problem.CodeBadRequest, fmt.Sprintf("unsupported query parameter: %s", key))
 			return
 		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+	params, err := parseListParams(q)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeBadRequest, err.Error())
+		return
+	}
+	resp, err := h.service.GetDeletedMovies(ctx, params.Page, params.Limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if params.Page*params.Limit < resp.Total {
+		next := pageURL(r.URL, params.Page+1)
+		resp.Links.Next = &next
+	}
+	if params.Page > 1 {
+		prev := pageURL(r.URL, params.Page-1)
+		resp.Links.Prev = &prev
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary Restore a deleted movie
+// @Description Undo a soft delete. Requires movies:purge (admin).
+// @Tags movies
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Movie ID"
+// @Success 200 {object} models.Movie
+// @Header 200 {string} ETag "New version of the movie"
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem "Movie not found in trash"
+// @Router /movies/{id}/restore [post]
+func (h *Handler) RestoreMovie(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	id := vars["id"]
+	username := middleware.PrincipalFromContext(ctx).Username
+	movie, err := h.service.RestoreMovie(ctx, id, username)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", etag(movie.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(movie)
+}
+
+// PurgeMovie handles DELETE /movies/{id}?hard=true, permanently removing a movie
+// (active or already in the trash). Documented together with DeleteMovie since they share a route.
+func (h *Handler) PurgeMovie(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if err := h.service.PurgeMovie(ctx, id); err != nil {
+		writeError(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
@@ -0,0 +1,120 @@
+package movie
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"go-flix-api/internal/middleware"
+	"go-flix-api/internal/rbac"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+)
+
+func TestParseListParams(t *testing.T) {
+	q, _ := url.ParseQuery("page=3&limit=5&tahun_rilis_max=2010&sort=judul&order=DESC")
+	params, err := parseListParams(q)
+	if err != nil {
+		t.Fatalf("parseListParams error: %v", err)
+	}
+	if params.Page != 3 || params.Limit != 5 || params.Order != "desc" || params.Sort != "judul" {
+		t.Fatalf("unexpected params: %+v", params)
+	}
+	if params.TahunRilisMax == nil || *params.TahunRilisMax != 2010 {
+		t.Fatalf("expected tahun_rilis_max=2010, got %v", params.TahunRilisMax)
+	}
+
+	defaults, err := parseListParams(url.Values{})
+	if err != nil || defaults.Page != 1 || defaults.Limit != defaultPageLimit {
+		t.Fatalf("unexpected defaults: %+v err=%v", defaults, err)
+	}
+
+	for _, raw := range []string{"page=0", "limit=1000", "sort=password", "order=sideways", "tahun_rilis_min=abc", "tahun_rilis_min=2020&tahun_rilis_max=2010", "cursor=&sort=judul", "cursor=abc&page=2"} {
+		q, _ := url.ParseQuery(raw)
+		if _, err := parseListParams(q); err == nil {
+			t.Fatalf("expected error for %q", raw)
+		}
+	}
+}
+
+func TestPageURL(t *testing.T) {
+	u, _ := url.Parse("/api/movies?genre=Action&page=2")
+	if got := pageURL(u, 3); got != "/api/movies?genre=Action&page=3" {
+		t.Fatalf("unexpected page url: %s", got)
+	}
+}
+
+func TestIfMatchVersion(t *testing.T) {
+	cases := map[string]struct {
+		want    int
+		wantNil bool
+		wantErr bool
+	}{
+		"":        {wantNil: true},
+		"*":       {wantNil: true},
+		`"v7"`:    {want: 7},
+		`W/"v7"`:  {wantErr: true},
+		`"abc"`:   {wantErr: true},
+		`"v7", *`: {wantErr: true},
+	}
+	for header, tc := range cases {
+		r := httptest.NewRequest("PUT", "/api/movies/x", nil)
+		if header != "" {
+			r.Header.Set("If-Match", header)
+		}
+		got, err := ifMatchVersion(r)
+		switch {
+		case tc.wantErr:
+			if err == nil {
+				t.Fatalf("%q: expected error", header)
+			}
+		case tc.wantNil:
+			if err != nil || got != nil {
+				t.Fatalf("%q: expected nil, got %v err=%v", header, got, err)
+			}
+		default:
+			if err != nil || got == nil || *got != tc.want {
+				t.Fatalf("%q: expected %d, got %v err=%v", header, tc.want, got, err)
+			}
+		}
+	}
+	if etag(7) != `"v7"` {
+		t.Fatalf("unexpected etag: %s", etag(7))
+	}
+}
+
+func TestGetMovieByIDStripsAuditForAnonymous(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	h := NewHandler(NewService(NewRepository(sqlx.NewDb(db, "sqlmock")), NewCursorCodec([]byte("test_secret"), time.Hour)))
+	id := "11111111-1111-1111-1111-111111111111"
+	cols := []string{"id", "judul", "genre", "tahun_rilis", "sutradara", "pemeran", "created_at", "updated_at", "deleted_at", "created_by", "updated_by", "version"}
+	for i := 0; i < 2; i++ {
+		mock.ExpectQuery(regexp.QuoteMeta("FROM movies WHERE id = $1 AND deleted_at IS NULL")).
+			WithArgs(id).
+			WillReturnRows(sqlmock.NewRows(cols).AddRow(id, "Judul", "G", 2000, "S", "{A}", time.Now(), time.Now(), nil, "alice", "bob", 1))
+	}
+
+	get := func(r *http.Request) string {
+		rec := httptest.NewRecorder()
+		h.GetMovieByID(rec, mux.SetURLVars(r, map[string]string{"id": id}))
+		return rec.Body.String()
+	}
+	if body := get(httptest.NewRequest(http.MethodGet, "/api/movies/"+id, nil)); strings.Contains(body, "alice") || strings.Contains(body, "created_by") {
+		t.Fatalf("expected audit fields stripped for anonymous caller, got %s", body)
+	}
+	authed := httptest.NewRequest(http.MethodGet, "/api/movies/"+id, nil)
+	authed = authed.WithContext(middleware.WithPrincipal(authed.Context(), middleware.Principal{Username: "carol", Role: rbac.RoleViewer}))
+	if body := get(authed); !strings.Contains(body, `"created_by":"alice"`) {
+		t.Fatalf("expected audit fields for authenticated caller, got %s", body)
+	}
+}
@@ -2,7 +2,9 @@ package movie
 
 import (
 	"context"
-	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"go-flix-api/models"
 
@@ -17,25 +19,153 @@ func NewRepository(db *sqlx.DB) *Repository {
 	return &Repository{db: db}
 }
 
-// FindAll returns all movies from the database
-func (r *Repository) FindAll(ctx context.Context) ([]models.Movie, error) {
-	var movies []models.Movie
-	query := `SELECT * FROM movies WHERE deleted_at IS NULL`
-	err := r.db.SelectContext(ctx, &movies, query)
-	return movies, err
+// movieColumns lists the columns mapped onto models.Movie.
+// Dipakai sebagai pengganti SELECT * karena tabel juga punya kolom search_vector.
+const movieColumns = `id, judul, genre, tahun_rilis, sutradara, pemeran,
+	created_at, updated_at, deleted_at, created_by, updated_by, deleted_by, version`
+
+// sortColumns is the whitelist of columns a client may sort the movie list by
+var sortColumns = map[string]string{
+	"judul":       "judul",
+	"genre":       "genre",
+	"tahun_rilis": "tahun_rilis",
+	"sutradara":   "sutradara",
+	"created_at":  "created_at",
+	"updated_at":  "updated_at",
+}
+
+// IsSortable reports whether field may be used as a sort key
+func IsSortable(field string) bool {
+	_, ok := sortColumns[field]
+	return ok
+}
+
+// buildListFilter translates the list params into a WHERE clause and its positional args
+func buildListFilter(params models.MovieListParams) (string, []interface{}) {
+	conditions := []string{"deleted_at IS NULL"}
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(cond, len(args)))
+	}
+	if params.Genre != "" {
+		add("LOWER(genre) = LOWER($%d)", params.Genre)
+	}
+	if params.TahunRilisMin != nil {
+		add("tahun_rilis >= $%d", *params.TahunRilisMin)
+	}
+	if params.TahunRilisMax != nil {
+		add("tahun_rilis <= $%d", *params.TahunRilisMax)
+	}
+	if params.Sutradara != "" {
+		add("sutradara ILIKE '%%' || $%d || '%%'", params.Sutradara)
+	}
+	if params.Pemeran != "" {
+		add("$%d = ANY(pemeran)", params.Pemeran)
+	}
+	return strings.Join(conditions, " AND "), args
 }
 
-// FindByID returns a movie by its ID
+// FindAll returns one page of movies matching the params, plus the total number of matches.
+// In cursor mode the total ignores the seek position, so it still counts every match.
+func (r *Repository) FindAll(ctx context.Context, params models.MovieListParams) ([]models.Movie, int, error) {
+	where, args := buildListFilter(params)
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM movies WHERE ` + where
+	if err := r.db.GetContext(ctx, &total, countQuery, args...); err != nil {
+		return nil, 0, translateError(err)
+	}
+
+	var query string
+	if params.CursorMode {
+		// Keyset pagination: seek past the cursor position instead of using OFFSET
+		if params.After != nil {
+			args = append(args, params.After.CreatedAt, params.After.ID)
+			where += fmt.Sprintf(" AND (created_at, id) < ($%d, $%d)", len(args)-1, len(args))
+		}
+		query = fmt.Sprintf(`SELECT %s FROM movies WHERE %s ORDER BY created_at DESC, id DESC LIMIT $%d`,
+			movieColumns, where, len(args)+1)
+		args = append(args, params.Limit)
+	} else {
+		column, ok := sortColumns[params.Sort]
+		if !ok {
+			column = "created_at"
+		}
+		direction := "ASC"
+		if strings.EqualFold(params.Order, "desc") {
+			direction = "DESC"
+		}
+		// id sebagai tie-breaker agar urutan antar halaman stabil
+		query = fmt.Sprintf(`SELECT %s FROM movies WHERE %s ORDER BY %s %s, id %s LIMIT $%d OFFSET $%d`,
+			movieColumns, where, column, direction, direction, len(args)+1, len(args)+2)
+		args = append(args, params.Limit, (params.Page-1)*params.Limit)
+	}
+
+	movies := []models.Movie{}
+	if err := r.db.SelectContext(ctx, &movies, query, args...); err != nil {
+		return nil, 0, translateError(err)
+	}
+	return movies, total, nil
+}
+
+// FindByID returns an active (not soft-deleted) movie by its ID, or ErrNotFound
 func (r *Repository) FindByID(ctx context.Context, id string) (*models.Movie, error) {
 	var movie models.Movie
-	query := `SELECT * FROM movies WHERE id = $1 AND deleted_at IS NULL`
+	query := `SELECT ` + movieColumns + ` FROM movies WHERE id = $1 AND deleted_at IS NULL`
 	err := r.db.GetContext(ctx, &movie, query, id)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err)
 	}
 	return &movie, nil
 }
 
+// FindByIDWithDeleted returns a movie by its ID even if it is in the trash, or ErrNotFound
+func (r *Repository) FindByIDWithDeleted(ctx context.Context, id string) (*models.Movie, error) {
+	var movie models.Movie
+	query := `SELECT ` + movieColumns + ` FROM movies WHERE id = $1`
+	err := r.db.GetContext(ctx, &movie, query, id)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &movie, nil
+}
+
+// Search runs a ranked full-text search over judul, sutradara and pemeran.
+// Snippet masih teks mentah dengan penanda markStart/markStop; Service yang meng-escape-nya.
+func (r *Repository) Search(ctx context.Context, q string, limit int) ([]models.MovieSearchResult, error) {
+	// Penanda dibuang dulu dari teks sumber agar data film tidak bisa memalsukan highlight
+	query := `SELECT ` + movieColumns + `,
+		ts_rank(search_vector, query) AS rank,
+		ts_headline('simple',
+			translate(judul || ' - ' || sutradara || ' - ' || array_to_string(pemeran, ', '), chr(1) || chr(2), ''),
+			query,
+			'StartSel=' || chr(1) || ', StopSel=' || chr(2) || ', MaxFragments=2, FragmentDelimiter=" ... "') AS snippet
+	FROM movies, websearch_to_tsquery('simple', $1) AS query
+	WHERE deleted_at IS NULL AND search_vector @@ query
+	ORDER BY rank DESC, id
+	LIMIT $2`
+	results := []models.MovieSearchResult{}
+	err := r.db.SelectContext(ctx, &results, query, q, limit)
+	return results, translateError(err)
+}
+
+// SearchFuzzy is the trigram fallback used when full-text search finds nothing, so typos still match.
+// Snippet berisi teks mentah judul, sutradara dan pemeran; Service yang menandai kata yang mirip.
+func (r *Repository) SearchFuzzy(ctx context.Context, q string, limit int) ([]models.MovieSearchResult, error) {
+	// Pemeran dicocokkan per kata (word_similarity), karena satu nama tenggelam dalam daftar pemeran yang panjang
+	query := `SELECT ` + movieColumns + `,
+		GREATEST(similarity(judul, $1), similarity(sutradara, $1), word_similarity($1, array_to_string(pemeran, ' '))) AS rank,
+		judul || ' - ' || sutradara || ' - ' || array_to_string(pemeran, ', ') AS snippet
+	FROM movies
+	WHERE deleted_at IS NULL AND (judul % $1 OR sutradara % $1 OR $1 <% array_to_string(pemeran, ' '))
+	ORDER BY rank DESC, id
+	LIMIT $2`
+	results := []models.MovieSearchResult{}
+	err := r.db.SelectContext(ctx, &results, query, q, limit)
+	return results, translateError(err)
+}
+
 // Save inserts a new movie into the database using an explicit transaction
 func (r *Repository) Save(ctx context.Context, movie models.Movie) error {
 	query := `INSERT INTO movies (
@@ -49,9 +179,9 @@ func (r *Repository) Save(ctx context.Context, movie models.Movie) error {
 	// 1. Mulai sesi transaksi baru
 	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return err
+		return translateError(err)
 	}
-	
+
 	// 2. Defer Rollback: Ini adalah jaring pengaman.
 	// Jika ada error di tengah jalan, transaksi akan otomatis dibatalkan.
 	defer tx.Rollback()
@@ -60,16 +190,66 @@ func (r *Repository) Save(ctx context.Context, movie models.Movie) error {
 	_, err = tx.NamedExecContext(ctx, query, movie)
 	if err != nil {
 		// Jika ada error di sini, Rollback akan otomatis terpanggil
-		return err
+		return translateError(err)
 	}
 
 	// 4. PENTING: Jika tidak ada error sama sekali, simpan permanen dengan COMMIT.
 	// Ini adalah tombol "Konfirmasi Akhir".
-	return tx.Commit()
+	return translateError(tx.Commit())
+}
+
+// FindDeleted returns one page of soft-deleted movies, most recently deleted first
+func (r *Repository) FindDeleted(ctx context.Context, page, limit int) ([]models.Movie, int, error) {
+	var total int
+	if err := r.db.GetContext(ctx, &total, `SELECT COUNT(*) FROM movies WHERE deleted_at IS NOT NULL`); err != nil {
+		return nil, 0, translateError(err)
+	}
+	query := `SELECT ` + movieColumns + ` FROM movies WHERE deleted_at IS NOT NULL
+	ORDER BY deleted_at DESC, id DESC LIMIT $1 OFFSET $2`
+	movies := []models.Movie{}
+	if err := r.db.SelectContext(ctx, &movies, query, limit, (page-1)*limit); err != nil {
+		return nil, 0, translateError(err)
+	}
+	return movies, total, nil
 }
 
-// Update updates an existing movie in the database
-func (r *Repository) Update(ctx context.Context, movie models.Movie) error {
+// Restore clears deleted_at on a soft-deleted movie and returns the restored row.
+// It returns ErrNotFound if no movie with that ID is in the trash.
+func (r *Repository) Restore(ctx context.Context, id string, restoredAt time.Time, restoredBy string) (*models.Movie, error) {
+	query := `UPDATE movies SET
+		deleted_at = NULL,
+		deleted_by = NULL,
+		updated_at = $1,
+		updated_by = $2,
+		version = version + 1
+	WHERE id = $3 AND deleted_at IS NOT NULL
+	RETURNING ` + movieColumns
+	var movie models.Movie
+	if err := r.db.GetContext(ctx, &movie, query, restoredAt, restoredBy, id); err != nil {
+		return nil, translateError(err)
+	}
+	return &movie, nil
+}
+
+// Purge permanently removes a movie, whether or not it was soft-deleted
+func (r *Repository) Purge(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM movies WHERE id = $1`, id)
+	if err != nil {
+		return translateError(err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Update updates an existing movie in the database.
+// The row is only written if its version still equals expectedVersion (optimistic locking).
+func (r *Repository) Update(ctx context.Context, movie models.Movie, expectedVersion int) error {
 	query := `UPDATE movies SET
 		judul = :judul,
 		genre = :genre,
@@ -79,34 +259,63 @@ func (r *Repository) Update(ctx context.Context, movie models.Movie) error {
 		updated_at = :updated_at,
 		updated_by = :updated_by,
 		version = :version
-	WHERE id = :id AND deleted_at IS NULL`
-	result, err := r.db.NamedExecContext(ctx, query, &movie)
+	WHERE id = :id AND deleted_at IS NULL AND version = :expected_version`
+	arg := struct {
+		models.Movie
+		ExpectedVersion int `db:"expected_version"`
+	}{movie, expectedVersion}
+	result, err := r.db.NamedExecContext(ctx, query, &arg)
 	if err != nil {
-		return err
+		return translateError(err)
 	}
 	n, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
 	if n == 0 {
-		return errors.New("no rows updated")
+		return r.missedWrite(ctx, movie.ID.String(), ErrNotFound)
 	}
 	return nil
 }
 
-// Delete performs a soft delete by setting deleted_at
-func (r *Repository) Delete(ctx context.Context, id string, deletedAt interface{}) error {
-	query := `UPDATE movies SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL`
-	result, err := r.db.ExecContext(ctx, query, deletedAt, id)
+// Delete performs a soft delete by setting deleted_at and deleted_by.
+// If expectedVersion is non-nil the delete is guarded by it; nil deletes whatever version is stored.
+// The version is bumped so a stale ETag cannot be used against the deleted (or later restored) row.
+func (r *Repository) Delete(ctx context.Context, id string, deletedAt time.Time, deletedBy string, expectedVersion *int) error {
+	query := `UPDATE movies SET
+		deleted_at = $1,
+		deleted_by = $2,
+		version = version + 1
+	WHERE id = $3 AND deleted_at IS NULL`
+	args := []interface{}{deletedAt, deletedBy, id}
+	if expectedVersion != nil {
+		query += ` AND version = $4`
+		args = append(args, *expectedVersion)
+	}
+	result, err := r.db.ExecContext(ctx, query, args...)
 	if err != nil {
-		return err
+		return translateError(err)
 	}
 	n, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
 	if n == 0 {
-		return errors.New("no rows deleted")
+		return r.missedWrite(ctx, id, ErrAlreadyDeleted)
 	}
 	return nil
 }
+
+// missedWrite explains why a guarded write touched no rows: ErrNotFound if the movie is gone,
+// whenDeleted if it is in the trash, otherwise another writer bumped its version first.
+func (r *Repository) missedWrite(ctx context.Context, id string, whenDeleted error) error {
+	var deletedAt *time.Time
+	query := `SELECT deleted_at FROM movies WHERE id = $1`
+	if err := r.db.GetContext(ctx, &deletedAt, query, id); err != nil {
+		return translateError(err)
+	}
+	if deletedAt != nil {
+		return whenDeleted
+	}
+	return ErrVersionMismatch
+}
@@ -2,9 +2,10 @@ package movie
 
 import (
 	"context"
-	"errors"
+	"fmt"
 	"time"
 
+	"go-flix-api/internal/validation"
 	"go-flix-api/models"
 
 	"github.com/google/uuid"
@@ -12,11 +13,29 @@ import (
 )
 
 type Service struct {
-	repo *Repository
+	repo    *Repository
+	cursors *CursorCodec
 }
 
-func NewService(repo *Repository) *Service {
-	return &Service{repo: repo}
+func NewService(repo *Repository, cursors *CursorCodec) *Service {
+	return &Service{repo: repo, cursors: cursors}
+}
+
+// checkID rejects IDs that are not UUIDs with ErrNotFound, since no movie can have them.
+// Tanpa ini PostgreSQL mengembalikan error "invalid input syntax for type uuid".
+func checkID(id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// validate runs the request validation, wrapping failures in ErrValidation
+func validate(req interface{}) error {
+	if err := validation.Struct(req); err != nil {
+		return fmt.Errorf("%w: %w", ErrValidation, err)
+	}
+	return nil
 }
 
 // ensureNotEmpty ensures that a string slice is never empty (to avoid null values in database)
@@ -27,8 +46,11 @@ func ensureNotEmpty(slice []string) []string {
 	return slice
 }
 
-// CreateMovie creates a new movie and saves it to the database
+// CreateMovie validates the request, then creates a new movie and saves it to the database
 func (s *Service) CreateMovie(ctx context.Context, req models.CreateMovieRequest) (*models.Movie, error) {
+	if err := validate(req); err != nil {
+		return nil, err
+	}
 	id := uuid.New()
 	now := time.Now()
 	movie := models.Movie{
@@ -51,22 +73,95 @@ func (s *Service) CreateMovie(ctx context.Context, req models.CreateMovieRequest
 	return &movie, nil
 }
 
-// GetAllMovies returns all movies
-func (s *Service) GetAllMovies(ctx context.Context) ([]models.Movie, error) {
-	return s.repo.FindAll(ctx)
+// GetAllMovies returns one page of movies matching the params and the total match count.
+// In cursor mode the raw cursor is verified first and a next cursor is issued when more rows remain.
+func (s *Service) GetAllMovies(ctx context.Context, params models.MovieListParams) (*models.MovieListResponse, error) {
+	if !params.CursorMode {
+		movies, total, err := s.repo.FindAll(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		return &models.MovieListResponse{Data: movies, Total: total, Page: params.Page, Limit: params.Limit}, nil
+	}
+
+	if params.Cursor != "" {
+		after, err := s.cursors.Decode(params.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		params.After = after
+	}
+	limit := params.Limit
+	// Ambil satu baris ekstra untuk mengetahui apakah masih ada halaman berikutnya
+	params.Limit = limit + 1
+	movies, total, err := s.repo.FindAll(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	resp := &models.MovieListResponse{Total: total, Limit: limit}
+	if len(movies) > limit {
+		movies = movies[:limit]
+		last := movies[len(movies)-1]
+		if resp.NextCursor, err = s.cursors.Encode(last.CreatedAt, last.ID); err != nil {
+			return nil, err
+		}
+	}
+	resp.Data = movies
+	return resp, nil
 }
 
 // GetMovieByID returns a movie by its ID
 func (s *Service) GetMovieByID(ctx context.Context, id string) (*models.Movie, error) {
+	if err := checkID(id); err != nil {
+		return nil, err
+	}
 	return s.repo.FindByID(ctx, id)
 }
 
-// UpdateMovie updates an existing movie
-func (s *Service) UpdateMovie(ctx context.Context, id string, req models.UpdateMovieRequest, username string) error {
+// SearchMovies returns movies matching q ranked by relevance.
+// If full-text search has no hits it falls back to trigram similarity and reports fuzzy=true.
+func (s *Service) SearchMovies(ctx context.Context, q string, limit int) (results []models.MovieSearchResult, fuzzy bool, err error) {
+	results, err = s.repo.Search(ctx, q, limit)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(results) > 0 {
+		for i := range results {
+			results[i].Snippet = highlight(results[i].Snippet)
+		}
+		return results, false, nil
+	}
+	results, err = s.repo.SearchFuzzy(ctx, q, limit)
+	if err != nil {
+		return nil, true, err
+	}
+	for i := range results {
+		results[i].Snippet = fuzzySnippet(results[i].Snippet, q)
+	}
+	return results, true, nil
+}
+
+// UpdateMovie validates the request, updates an existing movie and returns it with its new version.
+// If req.Version is set, the update is rejected with ErrVersionMismatch unless it equals the stored version.
+// Without it the write is still guarded by the version just read: the request is a partial update
+// merged onto that row, so a concurrent write in between fails with ErrVersionMismatch instead of
+// being overwritten with stale fields. DeleteMovie has nothing to merge and is unguarded without a version.
+func (s *Service) UpdateMovie(ctx context.Context, id string, req models.UpdateMovieRequest, username string) (*models.Movie, error) {
+	if err := validate(req); err != nil {
+		return nil, err
+	}
+	if err := checkID(id); err != nil {
+		return nil, err
+	}
 	movie, err := s.repo.FindByID(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if req.Version != nil && *req.Version != movie.Version {
+		return nil, ErrVersionMismatch
 	}
+	// Selalu dijaga, juga tanpa If-Match (lihat komentar di atas)
+	expectedVersion := movie.Version
 	if req.Judul != nil {
 		movie.Judul = *req.Judul
 	}
@@ -85,18 +180,55 @@ func (s *Service) UpdateMovie(ctx context.Context, id string, req models.UpdateM
 	movie.UpdatedAt = time.Now()
 	movie.UpdatedBy = &username
 	movie.Version++
-	return s.repo.Update(ctx, *movie)
+	if err := s.repo.Update(ctx, *movie, expectedVersion); err != nil {
+		return nil, err
+	}
+	return movie, nil
 }
 
-// DeleteMovie performs a soft delete
-func (s *Service) DeleteMovie(ctx context.Context, id string) error {
-	movie, err := s.repo.FindByID(ctx, id)
+// DeleteMovie performs a soft delete, recording username as deleted_by.
+// It returns ErrAlreadyDeleted if the movie is already in the trash.
+// If expectedVersion is non-nil the delete only happens when it equals the stored version.
+// Without it (no If-Match) a concurrent update does not make the delete fail.
+func (s *Service) DeleteMovie(ctx context.Context, id string, username string, expectedVersion *int) error {
+	if err := checkID(id); err != nil {
+		return err
+	}
+	movie, err := s.repo.FindByIDWithDeleted(ctx, id)
 	if err != nil {
 		return err
 	}
 	if movie.DeletedAt != nil {
-		return errors.New("movie already deleted")
+		return ErrAlreadyDeleted
+	}
+	if expectedVersion != nil && *expectedVersion != movie.Version {
+		return ErrVersionMismatch
 	}
 	deletedAt := time.Now()
-	return s.repo.Delete(ctx, id, deletedAt)
+	return s.repo.Delete(ctx, id, deletedAt, username, expectedVersion)
+}
+
+// GetDeletedMovies returns one page of soft-deleted movies (the trash bin)
+func (s *Service) GetDeletedMovies(ctx context.Context, page, limit int) (*models.MovieListResponse, error) {
+	movies, total, err := s.repo.FindDeleted(ctx, page, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &models.MovieListResponse{Data: movies, Total: total, Page: page, Limit: limit}, nil
+}
+
+// RestoreMovie undoes a soft delete
+func (s *Service) RestoreMovie(ctx context.Context, id string, username string) (*models.Movie, error) {
+	if err := checkID(id); err != nil {
+		return nil, err
+	}
+	return s.repo.Restore(ctx, id, time.Now(), username)
+}
+
+// PurgeMovie permanently deletes a movie
+func (s *Service) PurgeMovie(ctx context.Context, id string) error {
+	if err := checkID(id); err != nil {
+		return err
+	}
+	return s.repo.Purge(ctx, id)
 }
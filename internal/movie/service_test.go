@@ -2,13 +2,17 @@ package movie
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"regexp"
 	"testing"
 	"time"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 
+	"go-flix-api/internal/validation"
 	"go-flix-api/models"
 )
 
@@ -23,7 +27,7 @@ func TestCreateMovie(t *testing.T) {
 	defer db.Close()
 	sqlxDB := sqlx.NewDb(db, "sqlmock")
 	repo := NewRepository(sqlxDB)
-	svc := NewService(repo)
+	svc := NewService(repo, NewCursorCodec([]byte("test_secret"), time.Hour))
 
 	mock.ExpectBegin()
 	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO movies")).
@@ -55,12 +59,12 @@ func TestUpdateMovie(t *testing.T) {
 	defer db.Close()
 	sqlxDB := sqlx.NewDb(db, "sqlmock")
 	repo := NewRepository(sqlxDB)
-	svc := NewService(repo)
+	svc := NewService(repo, NewCursorCodec([]byte("test_secret"), time.Hour))
 
 	// FindByID
 	rows := sqlmock.NewRows([]string{"id", "judul", "genre", "tahun_rilis", "sutradara", "pemeran", "created_at", "updated_at", "deleted_at", "created_by", "updated_by", "version"}).
 		AddRow("11111111-1111-1111-1111-111111111111", "Old", "G", 2000, "S", "{A,B}", time.Now(), time.Now(), nil, nil, nil, 1)
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM movies WHERE id = $1 AND deleted_at IS NULL")).
+	mock.ExpectQuery(regexp.QuoteMeta("FROM movies WHERE id = $1 AND deleted_at IS NULL")).
 		WithArgs("11111111-1111-1111-1111-111111111111").
 		WillReturnRows(rows)
 
@@ -70,9 +74,344 @@ func TestUpdateMovie(t *testing.T) {
 
 	newTitle := "New"
 	req := models.UpdateMovieRequest{Judul: &newTitle}
-	if err := svc.UpdateMovie(context.Background(), "11111111-1111-1111-1111-111111111111", req, "tester"); err != nil {
+	updated, err := svc.UpdateMovie(context.Background(), "11111111-1111-1111-1111-111111111111", req, "tester")
+	if err != nil {
 		t.Fatalf("UpdateMovie error: %v", err)
 	}
+	if updated.Version != 2 {
+		t.Fatalf("expected version 2, got %d", updated.Version)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestGetAllMoviesWithFilters(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	repo := NewRepository(sqlxDB)
+	svc := NewService(repo, NewCursorCodec([]byte("test_secret"), time.Hour))
+
+	minYear := 2000
+	params := models.MovieListParams{
+		Page: 2, Limit: 10, Genre: "Sci-Fi", TahunRilisMin: &minYear, Pemeran: "Anne Hathaway",
+		Sort: "tahun_rilis", Order: "desc",
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM movies WHERE deleted_at IS NULL AND LOWER(genre) = LOWER($1) AND tahun_rilis >= $2 AND $3 = ANY(pemeran)")).
+		WithArgs("Sci-Fi", 2000, "Anne Hathaway").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(11))
+	rows := sqlmock.NewRows([]string{"id", "judul", "genre", "tahun_rilis", "sutradara", "pemeran", "created_at", "updated_at", "deleted_at", "created_by", "updated_by", "version"}).
+		AddRow("11111111-1111-1111-1111-111111111111", "Interstellar", "Sci-Fi", 2014, "Christopher Nolan", "{Anne Hathaway}", time.Now(), time.Now(), nil, nil, nil, 1)
+	mock.ExpectQuery(regexp.QuoteMeta("ORDER BY tahun_rilis DESC, id DESC LIMIT $4 OFFSET $5")).
+		WithArgs("Sci-Fi", 2000, "Anne Hathaway", 10, 10).
+		WillReturnRows(rows)
+
+	resp, err := svc.GetAllMovies(context.Background(), params)
+	if err != nil {
+		t.Fatalf("GetAllMovies error: %v", err)
+	}
+	if resp.Total != 11 || len(resp.Data) != 1 {
+		t.Fatalf("unexpected result: total=%d len=%d", resp.Total, len(resp.Data))
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestGetAllMoviesCursorMode(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	repo := NewRepository(sqlxDB)
+	cursors := NewCursorCodec([]byte("test_secret"), time.Hour)
+	svc := NewService(repo, cursors)
+
+	after := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	afterID := uuid.MustParse("33333333-3333-3333-3333-333333333333")
+	cursor, err := cursors.Encode(after, afterID)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM movies WHERE deleted_at IS NULL")).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+	cols := []string{"id", "judul", "genre", "tahun_rilis", "sutradara", "pemeran", "created_at", "updated_at", "deleted_at", "created_by", "updated_by", "version"}
+	rows := sqlmock.NewRows(cols).
+		AddRow("22222222-2222-2222-2222-222222222222", "B", "G", 2001, "S", "{A}", after.Add(-time.Minute), after, nil, nil, nil, 1).
+		AddRow("11111111-1111-1111-1111-111111111111", "A", "G", 2000, "S", "{A}", after.Add(-2*time.Minute), after, nil, nil, nil, 1)
+	mock.ExpectQuery(regexp.QuoteMeta("AND (created_at, id) < ($1, $2) ORDER BY created_at DESC, id DESC LIMIT $3")).
+		WithArgs(after, afterID, 2).
+		WillReturnRows(rows)
+
+	resp, err := svc.GetAllMovies(context.Background(), models.MovieListParams{Limit: 1, CursorMode: true, Cursor: cursor})
+	if err != nil {
+		t.Fatalf("GetAllMovies error: %v", err)
+	}
+	if len(resp.Data) != 1 || resp.Data[0].Judul != "B" || resp.NextCursor == "" {
+		t.Fatalf("unexpected page: %+v", resp)
+	}
+	next, err := cursors.Decode(resp.NextCursor)
+	if err != nil || next.ID.String() != "22222222-2222-2222-2222-222222222222" {
+		t.Fatalf("unexpected next cursor: %+v err=%v", next, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestSearchMoviesFallsBackToTrigram(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	repo := NewRepository(sqlxDB)
+	svc := NewService(repo, NewCursorCodec([]byte("test_secret"), time.Hour))
+
+	cols := []string{"id", "judul", "genre", "tahun_rilis", "sutradara", "pemeran", "created_at", "updated_at", "deleted_at", "created_by", "updated_by", "version", "rank", "snippet"}
+	mock.ExpectQuery(regexp.QuoteMeta("search_vector @@ query")).
+		WithArgs("Inteception", 20).
+		WillReturnRows(sqlmock.NewRows(cols))
+	mock.ExpectQuery(regexp.QuoteMeta("(judul % $1 OR sutradara % $1 OR $1 <% array_to_string(pemeran, ' '))")).
+		WithArgs("Inteception", 20).
+		WillReturnRows(sqlmock.NewRows(cols).
+			AddRow("11111111-1111-1111-1111-111111111111", "Inception", "Sci-Fi", 2010, "Christopher Nolan", "{Leonardo DiCaprio}", time.Now(), time.Now(), nil, nil, nil, 1, 0.47, "Inception - Christopher Nolan - Leonardo DiCaprio"))
+
+	results, fuzzy, err := svc.SearchMovies(context.Background(), "Inteception", 20)
+	if err != nil {
+		t.Fatalf("SearchMovies error: %v", err)
+	}
+	if !fuzzy || len(results) != 1 || results[0].Judul != "Inception" || results[0].Rank != 0.47 {
+		t.Fatalf("unexpected results: fuzzy=%v %+v", fuzzy, results)
+	}
+	if want := "<mark>Inception</mark> - Christopher Nolan - Leonardo DiCaprio"; results[0].Snippet != want {
+		t.Fatalf("unexpected snippet %q, want %q", results[0].Snippet, want)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestSearchSnippetEscapesMovieText(t *testing.T) {
+	raw := "\x01Alien\x02 - <img src=x onerror=alert(1)> - O'Brien & co"
+	want := "<mark>Alien</mark> - &lt;img src=x onerror=alert(1)&gt; - O&#39;Brien &amp; co"
+	if got := highlight(raw); got != want {
+		t.Fatalf("highlight = %q, want %q", got, want)
+	}
+
+	got := fuzzySnippet("<b>Inception</b> - Christopher Nolan - Leonardo DiCaprio", "leonardo dicapro")
+	want = "&lt;b&gt;Inception&lt;/b&gt; - Christopher Nolan - <mark>Leonardo</mark> <mark>DiCaprio</mark>"
+	if got != want {
+		t.Fatalf("fuzzySnippet = %q, want %q", got, want)
+	}
+}
+
+func TestUpdateMovieVersionMismatch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	repo := NewRepository(sqlxDB)
+	svc := NewService(repo, NewCursorCodec([]byte("test_secret"), time.Hour))
+	cols := []string{"id", "judul", "genre", "tahun_rilis", "sutradara", "pemeran", "created_at", "updated_at", "deleted_at", "created_by", "updated_by", "version"}
+	id := "11111111-1111-1111-1111-111111111111"
+
+	// Expected version in the request differs from the stored one: rejected before writing
+	mock.ExpectQuery(regexp.QuoteMeta("FROM movies WHERE id = $1 AND deleted_at IS NULL")).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(id, "Old", "G", 2000, "S", "{A}", time.Now(), time.Now(), nil, nil, nil, 3))
+	stale := 2
+	if _, err := svc.UpdateMovie(context.Background(), id, models.UpdateMovieRequest{Version: &stale}, "tester"); !errors.Is(err, ErrVersionMismatch) {
+		t.Fatalf("expected ErrVersionMismatch, got %v", err)
+	}
+
+	// No version in the request, but a concurrent writer bumps it between read and write:
+	// the UPDATE is still guarded by the version just read and misses
+	mock.ExpectQuery(regexp.QuoteMeta("FROM movies WHERE id = $1 AND deleted_at IS NULL")).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(id, "Old", "G", 2000, "S", "{A}", time.Now(), time.Now(), nil, nil, nil, 3))
+	mock.ExpectExec(regexp.QuoteMeta("AND version = ?")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT deleted_at FROM movies WHERE id = $1")).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"deleted_at"}).AddRow(nil))
+	if _, err := svc.UpdateMovie(context.Background(), id, models.UpdateMovieRequest{}, "tester"); !errors.Is(err, ErrVersionMismatch) {
+		t.Fatalf("expected ErrVersionMismatch, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestRestoreMovie(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	repo := NewRepository(sqlxDB)
+	svc := NewService(repo, NewCursorCodec([]byte("test_secret"), time.Hour))
+	cols := []string{"id", "judul", "genre", "tahun_rilis", "sutradara", "pemeran", "created_at", "updated_at", "deleted_at", "created_by", "updated_by", "version"}
+	id := "11111111-1111-1111-1111-111111111111"
+
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE id = $3 AND deleted_at IS NOT NULL")).
+		WithArgs(sqlmock.AnyArg(), "admin", id).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(id, "Back", "G", 2000, "S", "{A}", time.Now(), time.Now(), nil, nil, "admin", 4))
+	movie, err := svc.RestoreMovie(context.Background(), id, "admin")
+	if err != nil {
+		t.Fatalf("RestoreMovie error: %v", err)
+	}
+	if movie.DeletedAt != nil || movie.Version != 4 {
+		t.Fatalf("unexpected restored movie: %+v", movie)
+	}
+
+	// Movie that is not in the trash
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE id = $3 AND deleted_at IS NOT NULL")).
+		WithArgs(sqlmock.AnyArg(), "admin", id).
+		WillReturnRows(sqlmock.NewRows(cols))
+	if _, err := svc.RestoreMovie(context.Background(), id, "admin"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestDeleteMovieRecordsDeletedBy(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	repo := NewRepository(sqlxDB)
+	svc := NewService(repo, NewCursorCodec([]byte("test_secret"), time.Hour))
+	cols := []string{"id", "judul", "genre", "tahun_rilis", "sutradara", "pemeran", "created_at", "updated_at", "deleted_at", "created_by", "updated_by", "deleted_by", "version"}
+	id := "11111111-1111-1111-1111-111111111111"
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM movies WHERE id = $1")).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(id, "Old", "G", 2000, "S", "{A}", time.Now(), time.Now(), nil, nil, nil, nil, 5))
+	// Tanpa If-Match tidak ada guard versi, jadi update yang terjadi bersamaan tidak membuat delete gagal
+	mock.ExpectExec(regexp.QuoteMeta("deleted_by = $2,\n\t\tversion = version + 1\n\tWHERE id = $3 AND deleted_at IS NULL")).
+		WithArgs(sqlmock.AnyArg(), "tester", id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := svc.DeleteMovie(context.Background(), id, "tester", nil); err != nil {
+		t.Fatalf("DeleteMovie error: %v", err)
+	}
+
+	// Dengan If-Match versi ikut dicek di UPDATE
+	expected := 5
+	mock.ExpectQuery(regexp.QuoteMeta("FROM movies WHERE id = $1")).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(id, "Old", "G", 2000, "S", "{A}", time.Now(), time.Now(), nil, nil, nil, nil, 5))
+	mock.ExpectExec(regexp.QuoteMeta("WHERE id = $3 AND deleted_at IS NULL AND version = $4")).
+		WithArgs(sqlmock.AnyArg(), "tester", id, 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := svc.DeleteMovie(context.Background(), id, "tester", &expected); err != nil {
+		t.Fatalf("DeleteMovie with If-Match error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestCreateMovieRejectsInvalidRequest(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	repo := NewRepository(sqlxDB)
+	svc := NewService(repo, NewCursorCodec([]byte("test_secret"), time.Hour))
+
+	// Judul kosong dan tahun_rilis 0 tidak boleh sampai ke database
+	_, err = svc.CreateMovie(context.Background(), models.CreateMovieRequest{Genre: "Action", Sutradara: "Dir", Pemeran: []string{"A"}})
+	var verrs validation.Errors
+	if !errors.Is(err, ErrValidation) || !errors.As(err, &verrs) || len(verrs) != 2 {
+		t.Fatalf("expected ErrValidation with 2 field errors, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestGetMovieByIDErrors(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	repo := NewRepository(sqlxDB)
+	svc := NewService(repo, NewCursorCodec([]byte("test_secret"), time.Hour))
+	id := "11111111-1111-1111-1111-111111111111"
+
+	if _, err := svc.GetMovieByID(context.Background(), "not-a-uuid"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for malformed id, got %v", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM movies WHERE id = $1 AND deleted_at IS NULL")).
+		WithArgs(id).
+		WillReturnError(sql.ErrNoRows)
+	if _, err := svc.GetMovieByID(context.Background(), id); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// Gangguan database bukan 404
+	outage := errors.New("dial tcp 127.0.0.1:5432: connect: connection refused")
+	mock.ExpectQuery(regexp.QuoteMeta("FROM movies WHERE id = $1 AND deleted_at IS NULL")).
+		WithArgs(id).
+		WillReturnError(outage)
+	if _, err := svc.GetMovieByID(context.Background(), id); errors.Is(err, ErrNotFound) || !errors.Is(err, outage) {
+		t.Fatalf("expected the outage error to pass through, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestDeleteMovieAlreadyDeleted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	repo := NewRepository(sqlxDB)
+	svc := NewService(repo, NewCursorCodec([]byte("test_secret"), time.Hour))
+	cols := []string{"id", "judul", "genre", "tahun_rilis", "sutradara", "pemeran", "created_at", "updated_at", "deleted_at", "created_by", "updated_by", "deleted_by", "version"}
+	id := "11111111-1111-1111-1111-111111111111"
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM movies WHERE id = $1")).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(id, "Old", "G", 2000, "S", "{A}", time.Now(), time.Now(), time.Now(), nil, nil, "admin", 2))
+	if err := svc.DeleteMovie(context.Background(), id, "tester", nil); !errors.Is(err, ErrAlreadyDeleted) {
+		t.Fatalf("expected ErrAlreadyDeleted, got %v", err)
+	}
 
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Fatalf("unmet expectations: %v", err)
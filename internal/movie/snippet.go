@@ -0,0 +1,74 @@
+package movie
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// Penanda kata yang cocok dari ts_headline. Sengaja bukan tag HTML: teks film di-escape
+// dulu, baru penanda diganti <mark>, sehingga judul/pemeran tidak pernah menjadi markup.
+const (
+	markStart = "\x01"
+	markStop  = "\x02"
+)
+
+// fuzzyWordThreshold sama dengan pg_trgm.similarity_threshold default
+const fuzzyWordThreshold = 0.3
+
+var (
+	markReplacer = strings.NewReplacer(markStart, "<mark>", markStop, "</mark>")
+	wordPattern  = regexp.MustCompile(`[\p{L}\p{N}]+`)
+)
+
+// highlight escapes a ts_headline result and turns its markers into <mark> tags
+func highlight(raw string) string {
+	return markReplacer.Replace(html.EscapeString(raw))
+}
+
+// fuzzySnippet escapes text and wraps every word resembling a word of q in <mark>,
+// using the same trigram similarity as pg_trgm so typos like "Inteception" still light up
+func fuzzySnippet(text, q string) string {
+	var terms []map[string]bool
+	for _, w := range wordPattern.FindAllString(q, -1) {
+		terms = append(terms, trigrams(w))
+	}
+	var b strings.Builder
+	last := 0
+	for _, loc := range wordPattern.FindAllStringIndex(text, -1) {
+		b.WriteString(html.EscapeString(text[last:loc[0]]))
+		word := html.EscapeString(text[loc[0]:loc[1]])
+		if resembles(trigrams(text[loc[0]:loc[1]]), terms) {
+			word = "<mark>" + word + "</mark>"
+		}
+		b.WriteString(word)
+		last = loc[1]
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	return b.String()
+}
+
+func resembles(word map[string]bool, terms []map[string]bool) bool {
+	for _, term := range terms {
+		common := 0
+		for t := range word {
+			if term[t] {
+				common++
+			}
+		}
+		if float64(common)/float64(len(word)+len(term)-common) >= fuzzyWordThreshold {
+			return true
+		}
+	}
+	return false
+}
+
+// trigrams returns the pg_trgm trigrams of one word: lower case, padded "  word "
+func trigrams(word string) map[string]bool {
+	r := []rune("  " + strings.ToLower(word) + " ")
+	set := make(map[string]bool, len(r))
+	for i := 0; i+3 <= len(r); i++ {
+		set[string(r[i:i+3])] = true
+	}
+	return set
+}
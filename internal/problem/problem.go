@@ -0,0 +1,108 @@
+// Package problem writes RFC 7807 application/problem+json error responses.
+// Every error body carries a stable machine-readable Code so clients never have to parse Detail.
+package problem
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"go-flix-api/internal/validation"
+)
+
+// ContentType is the media type of every error response
+const ContentType = "application/problem+json"
+
+// RequestIDHeader is the header carrying the request ID, set by middleware.RequestID
+const RequestIDHeader = "X-Request-ID"
+
+// Stable error codes. Clients may switch on these; Title and Detail are for humans only.
+const (
+	CodeBadRequest          = "bad_request"
+	CodeInvalidJSON         = "invalid_json"
+	CodeInvalidCursor       = "invalid_cursor"
+	CodeValidationFailed    = "validation_failed"
+	CodeUnauthorized        = "unauthorized"
+	CodeInvalidCredentials  = "invalid_credentials"
+	CodeLoginThrottled      = "login_throttled"
+	CodeRateLimited         = "rate_limited"
+	CodeInvalidToken        = "invalid_token"
+	CodeTokenRevoked        = "token_revoked"
+	CodeInvalidRefreshToken = "invalid_refresh_token"
+	CodeRefreshTokenReused  = "refresh_token_reused"
+	CodeForbidden           = "forbidden"
+	CodeNotFound            = "not_found"
+	CodeMethodNotAllowed    = "method_not_allowed"
+	CodeMovieNotFound       = "movie_not_found"
+	CodeMovieDeleted        = "movie_deleted"
+	CodeUserNotFound        = "user_not_found"
+	CodeUsernameTaken       = "username_taken"
+	CodeAPIKeyNotFound      = "api_key_not_found"
+	CodeConflict            = "conflict"
+	CodePreconditionFailed  = "precondition_failed"
+	CodeInternal            = "internal_error"
+)
+
+// Problem is an RFC 7807 problem details object
+type Problem struct {
+	Type      string                  `json:"type" example:"about:blank"`
+	Title     string                  `json:"title" example:"Not Found"`
+	Status    int                     `json:"status" example:"404"`
+	Detail    string                  `json:"detail,omitempty" example:"Movie not found"`
+	Instance  string                  `json:"instance,omitempty" example:"/api/movies/11111111-1111-1111-1111-111111111111"`
+	Code      string                  `json:"code" example:"movie_not_found"`
+	RequestID string                  `json:"request_id,omitempty"`
+	Errors    []validation.FieldError `json:"errors,omitempty"`
+	// MissingPermission names the permission the caller lacked on a 403
+	MissingPermission string `json:"missing_permission,omitempty" example:"movies:write"`
+}
+
+// New creates a problem for status with a stable code and a human-readable detail
+func New(status int, code, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// Write sends p as the response, filling in the request path and request ID
+func Write(w http.ResponseWriter, r *http.Request, p *Problem) {
+	p.Instance = r.URL.Path
+	p.RequestID = r.Header.Get(RequestIDHeader)
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// Error is a shorthand for Write(w, r, New(status, code, detail))
+func Error(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	Write(w, r, New(status, code, detail))
+}
+
+// Validation writes a 422 listing every invalid field
+func Validation(w http.ResponseWriter, r *http.Request, errs validation.Errors) {
+	p := New(http.StatusUnprocessableEntity, CodeValidationFailed, "One or more fields are invalid")
+	p.Errors = errs
+	Write(w, r, p)
+}
+
+// Forbidden writes a 403 naming the permission the caller is missing
+func Forbidden(w http.ResponseWriter, r *http.Request, permission string) {
+	p := New(http.StatusForbidden, CodeForbidden, "Missing permission "+permission)
+	p.MissingPermission = permission
+	Write(w, r, p)
+}
+
+// Internal logs err and writes a generic 500 so internal details never reach the client
+func Internal(w http.ResponseWriter, r *http.Request, err error) {
+	slog.Error("Internal server error",
+		"error", err,
+		"method", r.Method,
+		"path", r.URL.Path,
+		"request_id", r.Header.Get(RequestIDHeader))
+	Error(w, r, http.StatusInternalServerError, CodeInternal, "An unexpected error occurred")
+}
@@ -0,0 +1,53 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-flix-api/internal/validation"
+)
+
+func TestWrite(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/movies/abc", nil)
+	r.Header.Set(RequestIDHeader, "req-123")
+	w := httptest.NewRecorder()
+
+	Error(w, r, http.StatusNotFound, CodeMovieNotFound, "Movie not found")
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != ContentType {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	var p Problem
+	if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if p.Code != CodeMovieNotFound || p.Status != 404 || p.Title != "Not Found" ||
+		p.Instance != "/api/movies/abc" || p.RequestID != "req-123" {
+		t.Fatalf("unexpected problem: %+v", p)
+	}
+}
+
+func TestValidationAndInternal(t *testing.T) {
+	r := httptest.NewRequest("POST", "/api/movies", nil)
+
+	w := httptest.NewRecorder()
+	Validation(w, r, validation.Errors{{Field: "judul", Rule: "required", Message: "is required"}})
+	var p Problem
+	json.NewDecoder(w.Body).Decode(&p)
+	if w.Code != http.StatusUnprocessableEntity || p.Code != CodeValidationFailed || len(p.Errors) != 1 {
+		t.Fatalf("unexpected validation problem: %d %+v", w.Code, p)
+	}
+
+	w = httptest.NewRecorder()
+	Internal(w, r, errors.New("pq: password authentication failed"))
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "pq:") {
+		t.Fatalf("internal error details leaked: %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// MemoryLimiter menyimpan bucket di memori proses.
+// Cocok untuk satu instance; setiap replika punya kuota sendiri.
+type MemoryLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]bucket
+	idle    time.Duration
+}
+
+// NewMemoryLimiter membuat limiter in-memory. Bucket yang tidak dipakai selama idle
+// (periode terpanjang dari semua rule) sudah pasti penuh dan dihapus oleh Sweep.
+func NewMemoryLimiter(idle time.Duration) *MemoryLimiter {
+	return &MemoryLimiter{buckets: make(map[string]bucket), idle: idle}
+}
+
+func (l *MemoryLimiter) Allow(ctx context.Context, key string, rule Rule, now time.Time) (Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	tokens := float64(rule.Limit)
+	if b, ok := l.buckets[key]; ok {
+		tokens = refill(rule, b.tokens, b.updated, now)
+	}
+	allowed := tokens >= 1
+	if allowed {
+		tokens--
+	}
+	l.buckets[key] = bucket{tokens: tokens, updated: now}
+	return newResult(rule, tokens, allowed), nil
+}
+
+func (l *MemoryLimiter) Sweep(ctx context.Context, now time.Time) (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var n int64
+	for key, b := range l.buckets {
+		if b.updated.Add(l.idle).Before(now) {
+			delete(l.buckets, key)
+			n++
+		}
+	}
+	return n, nil
+}
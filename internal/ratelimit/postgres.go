@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PostgresLimiter menyimpan bucket di tabel rate_limit_buckets,
+// sehingga kuota berlaku bersama untuk semua replika.
+type PostgresLimiter struct {
+	db   *sqlx.DB
+	idle time.Duration
+}
+
+// NewPostgresLimiter membuat limiter yang memakai tabel rate_limit_buckets.
+// idle sama seperti pada NewMemoryLimiter.
+func NewPostgresLimiter(db *sqlx.DB, idle time.Duration) *PostgresLimiter {
+	return &PostgresLimiter{db: db, idle: idle}
+}
+
+// refillSQL menghitung isi bucket saat ini: $2 = limit, $3 = now, $4 = token per detik
+const refillSQL = `LEAST($2::double precision, b.tokens + GREATEST(0, EXTRACT(EPOCH FROM ($3::timestamptz - b.updated_at))::double precision) * $4::double precision)`
+
+// Satu upsert atomik: isi ulang bucket, ambil satu token bila ada, dan kembalikan hasilnya
+const allowSQL = `INSERT INTO rate_limit_buckets AS b (key, tokens, allowed, updated_at)
+	VALUES ($1, $2::double precision - 1, TRUE, $3)
+	ON CONFLICT (key) DO UPDATE SET
+		tokens = CASE WHEN ` + refillSQL + ` >= 1 THEN ` + refillSQL + ` - 1 ELSE ` + refillSQL + ` END,
+		allowed = ` + refillSQL + ` >= 1,
+		updated_at = $3
+	RETURNING tokens, allowed`
+
+func (l *PostgresLimiter) Allow(ctx context.Context, key string, rule Rule, now time.Time) (Result, error) {
+	var row struct {
+		Tokens  float64 `db:"tokens"`
+		Allowed bool    `db:"allowed"`
+	}
+	if err := l.db.GetContext(ctx, &row, allowSQL, key, rule.Limit, now, rule.rate()); err != nil {
+		return Result{}, err
+	}
+	return newResult(rule, row.Tokens, row.Allowed), nil
+}
+
+func (l *PostgresLimiter) Sweep(ctx context.Context, now time.Time) (int64, error) {
+	result, err := l.db.ExecContext(ctx, `DELETE FROM rate_limit_buckets WHERE updated_at < $1`, now.Add(-l.idle))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
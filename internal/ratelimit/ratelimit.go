@@ -0,0 +1,61 @@
+// Package ratelimit implements token-bucket rate limiting with in-memory and PostgreSQL storage.
+// Every key has a bucket of Rule.Limit tokens that refills evenly over Rule.Period;
+// each request takes one token and is refused when the bucket is empty.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Rule is the quota of one route group: Limit requests per Period, with bursts up to Limit
+type Rule struct {
+	Limit  int
+	Period time.Duration
+}
+
+// rate returns the refill rate in tokens per second
+func (r Rule) rate() float64 {
+	return float64(r.Limit) / r.Period.Seconds()
+}
+
+// Result describes a bucket after one request
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int           // whole tokens left
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next token, zero when Allowed
+}
+
+// Limiter takes one token from the bucket of key
+type Limiter interface {
+	Allow(ctx context.Context, key string, rule Rule, now time.Time) (Result, error)
+	// Sweep menghapus bucket yang sudah penuh kembali (tidak dipakai selama periode terpanjang)
+	Sweep(ctx context.Context, now time.Time) (int64, error)
+}
+
+// refill returns the tokens in a bucket that had tokens at updated
+func refill(rule Rule, tokens float64, updated, now time.Time) float64 {
+	elapsed := max(now.Sub(updated).Seconds(), 0)
+	return min(float64(rule.Limit), tokens+elapsed*rule.rate())
+}
+
+func newResult(rule Rule, tokens float64, allowed bool) Result {
+	rate := rule.rate()
+	res := Result{
+		Allowed:   allowed,
+		Limit:     rule.Limit,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(rule.Limit) - tokens) / rate),
+	}
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(max(s, 0) * float64(time.Second))
+}
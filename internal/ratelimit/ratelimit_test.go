@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+)
+
+func TestMemoryLimiterTokenBucket(t *testing.T) {
+	ctx := context.Background()
+	l := NewMemoryLimiter(time.Minute)
+	rule := Rule{Limit: 3, Period: 3 * time.Second} // 1 token per detik
+	now := time.Now()
+
+	for i := 2; i >= 0; i-- {
+		res, _ := l.Allow(ctx, "ip:1.2.3.4", rule, now)
+		if !res.Allowed || res.Remaining != i || res.Limit != 3 {
+			t.Fatalf("expected burst request allowed with %d remaining, got %+v", i, res)
+		}
+	}
+	res, _ := l.Allow(ctx, "ip:1.2.3.4", rule, now)
+	if res.Allowed || res.RetryAfter != time.Second || res.Reset != 3*time.Second {
+		t.Fatalf("expected refusal with 1s retry and 3s reset, got %+v", res)
+	}
+	// Bucket lain tidak terpengaruh
+	if res, _ := l.Allow(ctx, "ip:5.6.7.8", rule, now); !res.Allowed {
+		t.Fatalf("expected other key allowed, got %+v", res)
+	}
+	// Satu token kembali setelah satu detik
+	res, _ = l.Allow(ctx, "ip:1.2.3.4", rule, now.Add(1500*time.Millisecond))
+	if !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("expected refilled token, got %+v", res)
+	}
+
+	if n, _ := l.Sweep(ctx, now.Add(2*time.Minute)); n != 2 {
+		t.Fatalf("expected 2 idle buckets swept, got %d", n)
+	}
+}
+
+func TestPostgresLimiter(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+	l := NewPostgresLimiter(sqlx.NewDb(db, "sqlmock"), time.Minute)
+	ctx := context.Background()
+	now := time.Now()
+	rule := Rule{Limit: 60, Period: time.Minute}
+
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO rate_limit_buckets")).
+		WithArgs("api:user:user1", 60, now, 1.0).
+		WillReturnRows(sqlmock.NewRows([]string{"tokens", "allowed"}).AddRow(0.25, false))
+	res, err := l.Allow(ctx, "api:user:user1", rule, now)
+	if err != nil || res.Allowed || res.Remaining != 0 || res.RetryAfter != 750*time.Millisecond {
+		t.Fatalf("expected refusal with 750ms retry, got %+v err=%v", res, err)
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM rate_limit_buckets WHERE updated_at < $1")).
+		WithArgs(now.Add(-time.Minute)).WillReturnResult(sqlmock.NewResult(0, 3))
+	if n, err := l.Sweep(ctx, now); err != nil || n != 3 {
+		t.Fatalf("expected 3 swept, got %d err=%v", n, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
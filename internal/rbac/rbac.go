@@ -0,0 +1,56 @@
+// Package rbac defines the roles a user can have and the permissions each role grants.
+// Routes are guarded by permission, never by role name, so adding a role only touches this file.
+package rbac
+
+// Roles, from least to most privileged.
+// RoleAnonymous is never stored with a user; it is what unauthenticated requests are judged as.
+const (
+	RoleAnonymous = "anonymous"
+	RoleViewer    = "viewer"
+	RoleEditor    = "editor"
+	RoleAdmin     = "admin"
+)
+
+// Permission is a single capability checked by middleware.RequirePermission
+type Permission string
+
+const (
+	// MoviesRead allows listing, searching and fetching movies
+	MoviesRead Permission = "movies:read"
+	// MoviesWrite allows creating, updating and soft-deleting movies
+	MoviesWrite Permission = "movies:write"
+	// MoviesPurge allows the trash bin: listing, restoring and hard-deleting movies
+	MoviesPurge Permission = "movies:purge"
+	// UsersManage allows the admin user-management endpoints
+	UsersManage Permission = "users:manage"
+	// APIKeysManage allows creating, listing and revoking API keys
+	APIKeysManage Permission = "api_keys:manage"
+)
+
+var rolePermissions = map[string][]Permission{
+	RoleAnonymous: {MoviesRead}, // katalog film publik
+	RoleViewer:    {MoviesRead},
+	RoleEditor:    {MoviesRead, MoviesWrite},
+	RoleAdmin:     {MoviesRead, MoviesWrite, MoviesPurge, UsersManage, APIKeysManage},
+}
+
+// Valid reports whether role can be assigned to a user
+func Valid(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok && role != RoleAnonymous
+}
+
+// Permissions returns the permissions granted to role, or nil for an unknown role
+func Permissions(role string) []Permission {
+	return rolePermissions[role]
+}
+
+// Has reports whether role grants permission p. Unknown roles grant nothing.
+func Has(role string, p Permission) bool {
+	for _, granted := range rolePermissions[role] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
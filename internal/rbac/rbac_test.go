@@ -0,0 +1,33 @@
+package rbac
+
+import "testing"
+
+func TestHas(t *testing.T) {
+	cases := []struct {
+		role string
+		perm Permission
+		want bool
+	}{
+		{RoleViewer, MoviesRead, true},
+		{RoleViewer, MoviesWrite, false},
+		{RoleEditor, MoviesWrite, true},
+		{RoleEditor, MoviesPurge, false},
+		{RoleAdmin, MoviesPurge, true},
+		{RoleAdmin, UsersManage, true},
+		{RoleAnonymous, MoviesRead, true},
+		{RoleAnonymous, MoviesWrite, false},
+		{"user", MoviesRead, false},
+		{"", MoviesRead, false},
+	}
+	for _, c := range cases {
+		if got := Has(c.role, c.perm); got != c.want {
+			t.Fatalf("Has(%q, %q) = %v, want %v", c.role, c.perm, got, c.want)
+		}
+	}
+}
+
+func TestValid(t *testing.T) {
+	if !Valid(RoleEditor) || Valid(RoleAnonymous) || Valid("user") {
+		t.Fatalf("expected only assignable roles to be valid")
+	}
+}
@@ -0,0 +1,12 @@
+package signing
+
+import "github.com/golang-jwt/jwt/v5"
+
+// Claims is the payload of every access token. Issued by auth.Service and
+// verified by the Bearer and session cookie strategies in the middleware package.
+type Claims struct {
+	Username  string `json:"username"`
+	Role      string `json:"role"`          // rbac role at the time the token was issued
+	SessionID string `json:"sid,omitempty"` // refresh token family the access token belongs to
+	jwt.RegisteredClaims
+}
@@ -0,0 +1,56 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"sort"
+)
+
+// JWK is one public key in RFC 7517 JSON Web Key format
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is the document served at /.well-known/jwks.json
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public half of every asymmetric key, sorted by kid.
+// HMAC secrets are never published.
+func (ks *KeySet) JWKS() JWKS {
+	out := JWKS{Keys: []JWK{}}
+	for _, k := range ks.keys {
+		jwk := JWK{Kid: k.ID, Use: "sig", Alg: k.Method.Alg()}
+		switch pub := k.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = b64(pub.N.Bytes())
+			jwk.E = b64(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = b64(pub)
+		default:
+			continue
+		}
+		out.Keys = append(out.Keys, jwk)
+	}
+	sort.Slice(out.Keys, func(i, j int) bool { return out.Keys[i].Kid < out.Keys[j].Kid })
+	return out
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
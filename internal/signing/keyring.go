@@ -0,0 +1,113 @@
+package signing
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Keyring is the live key set of a running server. Rotate swaps in a new set
+// atomically; tokens signed by a replaced set stay valid until its grace period ends.
+type Keyring struct {
+	state atomic.Pointer[ringState]
+	now   func() time.Time
+}
+
+type ringState struct {
+	current  *KeySet
+	previous []retiredSet // set yang diganti dan masih dalam masa tenggang, terbaru dulu
+}
+
+// retiredSet is a replaced key set that still verifies tokens until until
+type retiredSet struct {
+	keys  *KeySet
+	until time.Time
+}
+
+// live returns the previous sets whose grace period has not ended at now
+func (s *ringState) live(now time.Time) []*KeySet {
+	var out []*KeySet
+	for _, p := range s.previous {
+		if now.Before(p.until) {
+			out = append(out, p.keys)
+		}
+	}
+	return out
+}
+
+// NewKeyring returns a keyring holding ks
+func NewKeyring(ks *KeySet) *Keyring {
+	r := &Keyring{now: time.Now}
+	r.state.Store(&ringState{current: ks})
+	return r
+}
+
+// Current returns the key set used to sign new tokens
+func (r *Keyring) Current() *KeySet {
+	return r.state.Load().current
+}
+
+// Rotate makes next the signing set. The replaced set keeps verifying tokens for grace;
+// a grace of zero drops it immediately. Sets replaced by earlier rotations keep their own
+// grace period, so rotating twice in quick succession does not cut off the oldest tokens.
+func (r *Keyring) Rotate(next *KeySet, grace time.Duration) {
+	now := r.now()
+	old := r.state.Load()
+	s := &ringState{current: next}
+	if grace > 0 {
+		s.previous = append(s.previous, retiredSet{keys: old.current, until: now.Add(grace)})
+	}
+	for _, p := range old.previous {
+		if now.Before(p.until) {
+			s.previous = append(s.previous, p)
+		}
+	}
+	r.state.Store(s)
+}
+
+// Sign signs claims with the current signing key
+func (r *Keyring) Sign(claims jwt.Claims) (string, error) {
+	return r.Current().Sign(claims)
+}
+
+// Stamp sets iss and aud from the current validation settings
+func (r *Keyring) Stamp(claims *jwt.RegisteredClaims) {
+	r.Current().Stamp(claims)
+}
+
+// Parse verifies tokenStr with the current set, falling back to the previous sets
+// still in their grace period. On failure the error from the current set is returned.
+func (r *Keyring) Parse(tokenStr string, claims jwt.Claims) (*jwt.Token, error) {
+	s := r.state.Load()
+	token, err := s.current.Parse(tokenStr, claims)
+	if err == nil {
+		return token, nil
+	}
+	for _, ks := range s.live(r.now()) {
+		if token, prevErr := ks.Parse(tokenStr, claims); prevErr == nil {
+			return token, nil
+		}
+	}
+	return token, err
+}
+
+// JWKS publishes the current keys plus the previous ones still in their grace period
+// so other verifiers keep accepting tokens signed before the rotation.
+func (r *Keyring) JWKS() JWKS {
+	s := r.state.Load()
+	out := s.current.JWKS()
+	seen := map[string]bool{}
+	for _, k := range out.Keys {
+		seen[k.Kid] = true
+	}
+	for _, ks := range s.live(r.now()) {
+		for _, k := range ks.JWKS().Keys {
+			if !seen[k.Kid] {
+				seen[k.Kid] = true
+				out.Keys = append(out.Keys, k)
+			}
+		}
+	}
+	return out
+}
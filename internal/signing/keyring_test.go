@@ -0,0 +1,84 @@
+package signing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestKeyringRotationGracePeriod(t *testing.T) {
+	now := time.Now()
+	ring := NewKeyring(NewHMACKeySet([]byte("old_secret")))
+	ring.now = func() time.Time { return now }
+
+	signed := func() string {
+		c := claims()
+		ring.Stamp(&c)
+		token, err := ring.Sign(c)
+		if err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+		return token
+	}
+	oldToken := signed()
+
+	ring.Rotate(NewHMACKeySet([]byte("new_secret")), time.Minute)
+	newToken := signed()
+	for name, token := range map[string]string{"old": oldToken, "new": newToken} {
+		if _, err := ring.Parse(token, &jwt.RegisteredClaims{}); err != nil {
+			t.Fatalf("%s token rejected during grace period: %v", name, err)
+		}
+	}
+
+	now = now.Add(time.Minute)
+	if _, err := ring.Parse(oldToken, &jwt.RegisteredClaims{}); err == nil {
+		t.Fatal("old token accepted after grace period")
+	}
+	if _, err := ring.Parse(newToken, &jwt.RegisteredClaims{}); err != nil {
+		t.Fatalf("new token rejected: %v", err)
+	}
+
+	// Tanpa masa tenggang kunci lama langsung ditolak
+	ring.Rotate(NewHMACKeySet([]byte("newest_secret")), 0)
+	if _, err := ring.Parse(newToken, &jwt.RegisteredClaims{}); err == nil {
+		t.Fatal("token of replaced key accepted without grace period")
+	}
+}
+
+func TestKeyringRotateTwiceWithinGracePeriod(t *testing.T) {
+	now := time.Now()
+	ring := NewKeyring(NewHMACKeySet([]byte("first_secret")))
+	ring.now = func() time.Time { return now }
+	signed := func() string {
+		c := claims()
+		ring.Stamp(&c)
+		token, err := ring.Sign(c)
+		if err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+		return token
+	}
+	first := signed()
+
+	ring.Rotate(NewHMACKeySet([]byte("second_secret")), time.Minute)
+	second := signed()
+	now = now.Add(30 * time.Second)
+	ring.Rotate(NewHMACKeySet([]byte("third_secret")), time.Minute)
+
+	// Rotasi kedua tidak boleh memotong masa tenggang kunci pertama
+	for name, token := range map[string]string{"first": first, "second": second} {
+		if _, err := ring.Parse(token, &jwt.RegisteredClaims{}); err != nil {
+			t.Fatalf("%s token rejected during its grace period: %v", name, err)
+		}
+	}
+
+	// Setiap set punya batas waktunya sendiri
+	now = now.Add(30 * time.Second)
+	if _, err := ring.Parse(first, &jwt.RegisteredClaims{}); err == nil {
+		t.Fatal("first token accepted after its grace period")
+	}
+	if _, err := ring.Parse(second, &jwt.RegisteredClaims{}); err != nil {
+		t.Fatalf("second token rejected during its grace period: %v", err)
+	}
+}
@@ -0,0 +1,238 @@
+// Package signing holds the keys used to sign and verify JWTs.
+// Every token carries a kid header naming its key, so several keys can be
+// trusted at once while a new signing key is rolled out.
+package signing
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"go-flix-api/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRSABits is the smallest RSA modulus accepted for signing or verification
+const minRSABits = 2048
+
+var (
+	// ErrUnknownKey is returned for tokens whose kid is not in the key set
+	ErrUnknownKey = errors.New("unknown signing key")
+	// ErrAlgorithmMismatch is returned when a token's alg differs from its key's algorithm,
+	// e.g. an HS256 token signed with an RSA public key as the HMAC secret
+	ErrAlgorithmMismatch = errors.New("token algorithm does not match key")
+)
+
+// Key is one signing or verification key
+type Key struct {
+	ID         string
+	Method     jwt.SigningMethod
+	signingKey interface{} // nil for verify-only keys
+	verifyKey  interface{}
+}
+
+// KeySet is the set of trusted keys plus the one used to sign new tokens
+type KeySet struct {
+	signer     *Key
+	keys       map[string]*Key
+	validation Validation
+}
+
+// NewHMACKeySet returns a key set with a single HS256 secret and no kid.
+// Dipakai bila jwt.keys tidak diisi, sama seperti perilaku sebelumnya.
+func NewHMACKeySet(secret []byte) *KeySet {
+	k := &Key{ID: "", Method: jwt.SigningMethodHS256, signingKey: secret, verifyKey: secret}
+	ks := &KeySet{signer: k, keys: map[string]*Key{"": k}}
+	ks.SetValidation(Validation{})
+	return ks
+}
+
+// NewKeySet builds a key set from keys, signing with the key whose ID is signingID
+func NewKeySet(signingID string, keys ...*Key) (*KeySet, error) {
+	ks := &KeySet{keys: make(map[string]*Key, len(keys))}
+	for _, k := range keys {
+		if k.ID == "" {
+			return nil, errors.New("every key needs an id")
+		}
+		if _, dup := ks.keys[k.ID]; dup {
+			return nil, fmt.Errorf("duplicate key id %q", k.ID)
+		}
+		ks.keys[k.ID] = k
+	}
+	signer, ok := ks.keys[signingID]
+	if !ok {
+		return nil, fmt.Errorf("signing key %q is not configured", signingID)
+	}
+	if signer.signingKey == nil {
+		return nil, fmt.Errorf("signing key %q has no private key", signingID)
+	}
+	ks.signer = signer
+	if err := ks.SetValidation(Validation{}); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// Load builds the key set described by cfg: the configured PEM keys if any,
+// otherwise the HS256 secret, with the issuer, audience, algorithm and leeway checks applied
+func Load(cfg config.JWTConfig) (*KeySet, error) {
+	ks, err := loadKeys(cfg)
+	if err != nil {
+		return nil, err
+	}
+	err = ks.SetValidation(Validation{
+		Issuer:     cfg.Issuer,
+		Audience:   cfg.Audience,
+		Algorithms: cfg.Algorithms,
+		Leeway:     cfg.Leeway,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("jwt: %w", err)
+	}
+	return ks, nil
+}
+
+func loadKeys(cfg config.JWTConfig) (*KeySet, error) {
+	if len(cfg.Keys) == 0 {
+		if cfg.Secret == "" {
+			return nil, errors.New("jwt.secret or jwt.keys is required")
+		}
+		return NewHMACKeySet([]byte(cfg.Secret)), nil
+	}
+	keys := make([]*Key, 0, len(cfg.Keys))
+	for _, kc := range cfg.Keys {
+		k, err := loadKey(kc)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", kc.ID, err)
+		}
+		keys = append(keys, k)
+	}
+	return NewKeySet(cfg.SigningKeyID, keys...)
+}
+
+func loadKey(kc config.JWTKeyConfig) (*Key, error) {
+	switch {
+	case kc.PrivateKeyFile != "":
+		data, err := os.ReadFile(kc.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		return ParsePrivateKeyPEM(kc.ID, data)
+	case kc.PublicKeyFile != "":
+		data, err := os.ReadFile(kc.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		return ParsePublicKeyPEM(kc.ID, data)
+	default:
+		return nil, errors.New("private_key_file or public_key_file is required")
+	}
+}
+
+// ParsePrivateKeyPEM parses an RSA or Ed25519 private key (PKCS#8, or PKCS#1 for RSA).
+// The algorithm follows from the key type: RS256 for RSA, EdDSA for Ed25519.
+func ParsePrivateKeyPEM(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	var priv interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		priv, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		priv, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := priv.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", priv)
+	}
+	k, err := newKey(id, signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	k.signingKey = signer
+	return k, nil
+}
+
+// ParsePublicKeyPEM parses an RSA or Ed25519 public key (PKIX) as a verify-only key
+func ParsePublicKeyPEM(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	if block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	return newKey(id, pub)
+}
+
+func newKey(id string, pub crypto.PublicKey) (*Key, error) {
+	switch p := pub.(type) {
+	case *rsa.PublicKey:
+		if p.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("RSA key is %d bits, at least %d required", p.N.BitLen(), minRSABits)
+		}
+		return &Key{ID: id, Method: jwt.SigningMethodRS256, verifyKey: p}, nil
+	case ed25519.PublicKey:
+		return &Key{ID: id, Method: jwt.SigningMethodEdDSA, verifyKey: p}, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T (use RSA or Ed25519)", pub)
+	}
+}
+
+// Sign signs claims with the signing key, setting the kid header
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.signer.Method, claims)
+	if ks.signer.ID != "" {
+		token.Header["kid"] = ks.signer.ID
+	}
+	return token.SignedString(ks.signer.signingKey)
+}
+
+// Keyfunc is a jwt.Keyfunc selecting the verification key by the token's kid header
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, ErrAlgorithmMismatch
+	}
+	return key.verifyKey, nil
+}
+
+// Algorithms returns the alg values tokens may use
+func (ks *KeySet) Algorithms() []string {
+	return ks.validation.Algorithms
+}
+
+// keyAlgorithms returns the alg values of all trusted keys
+func (ks *KeySet) keyAlgorithms() []string {
+	seen := map[string]bool{}
+	var algs []string
+	for _, k := range ks.keys {
+		if alg := k.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			algs = append(algs, alg)
+		}
+	}
+	return algs
+}
@@ -0,0 +1,145 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-flix-api/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func privatePEM(t *testing.T, key interface{}) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal private key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func publicPEM(t *testing.T, key interface{}) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatalf("marshal public key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func parse(ks *KeySet, token string) error {
+	_, err := jwt.Parse(token, ks.Keyfunc, jwt.WithValidMethods(ks.Algorithms()))
+	return err
+}
+
+func claims() jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{Subject: "user1", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))}
+}
+
+func TestRotationKeepsOldTokensValid(t *testing.T) {
+	_, edPriv, _ := ed25519.GenerateKey(rand.Reader)
+	rsaPriv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate RSA key: %v", err)
+	}
+	oldKey, err := ParsePrivateKeyPEM("old", privatePEM(t, edPriv))
+	if err != nil {
+		t.Fatalf("parse Ed25519 key: %v", err)
+	}
+	oldSet, _ := NewKeySet("old", oldKey)
+	oldToken, _ := oldSet.Sign(claims())
+
+	// Kunci baru dipakai untuk menandatangani, kunci lama tinggal public key-nya
+	newKey, err := ParsePrivateKeyPEM("new", privatePEM(t, rsaPriv))
+	if err != nil {
+		t.Fatalf("parse RSA key: %v", err)
+	}
+	retired, err := ParsePublicKeyPEM("old", publicPEM(t, edPriv.Public()))
+	if err != nil {
+		t.Fatalf("parse public key: %v", err)
+	}
+	ks, err := NewKeySet("new", newKey, retired)
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+	newToken, _ := ks.Sign(claims())
+
+	if err := parse(ks, oldToken); err != nil {
+		t.Fatalf("expected token from retired key to verify, got %v", err)
+	}
+	if err := parse(ks, newToken); err != nil {
+		t.Fatalf("expected token from new key to verify, got %v", err)
+	}
+	tok, _, _ := jwt.NewParser().ParseUnverified(newToken, &jwt.RegisteredClaims{})
+	if tok.Header["kid"] != "new" || tok.Method.Alg() != "RS256" {
+		t.Fatalf("expected kid=new alg=RS256, got %v", tok.Header)
+	}
+	if _, err := NewKeySet("old", retired); err == nil {
+		t.Fatalf("expected error when the signing key has no private key")
+	}
+
+	jwks := ks.JWKS()
+	if len(jwks.Keys) != 2 || jwks.Keys[0].Kid != "new" || jwks.Keys[0].Kty != "RSA" || jwks.Keys[1].Crv != "Ed25519" {
+		t.Fatalf("unexpected JWKS: %+v", jwks)
+	}
+}
+
+func TestKeyfuncRejectsUnknownKidAndAlgorithmConfusion(t *testing.T) {
+	_, edPriv, _ := ed25519.GenerateKey(rand.Reader)
+	key, _ := ParsePrivateKeyPEM("k1", privatePEM(t, edPriv))
+	ks, _ := NewKeySet("k1", key)
+
+	other := NewHMACKeySet([]byte("secret"))
+	hsToken, _ := other.Sign(claims())
+	if err := parse(ks, hsToken); err == nil {
+		t.Fatalf("expected HS256 token rejected by an EdDSA key set")
+	}
+
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, claims())
+	forged.Header["kid"] = "k1"
+	forgedStr, _ := forged.SignedString([]byte(edPriv.Public().(ed25519.PublicKey)))
+	if _, err := ks.Keyfunc(forged); !errors.Is(err, ErrAlgorithmMismatch) {
+		t.Fatalf("expected ErrAlgorithmMismatch, got %v", err)
+	}
+	if err := parse(ks, forgedStr); err == nil {
+		t.Fatalf("expected forged HS256 token rejected")
+	}
+
+	unknown := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims())
+	unknown.Header["kid"] = "k2"
+	if _, err := ks.Keyfunc(unknown); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("expected ErrUnknownKey, got %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	_, edPriv, _ := ed25519.GenerateKey(rand.Reader)
+	path := filepath.Join(dir, "k1.pem")
+	if err := os.WriteFile(path, privatePEM(t, edPriv), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	ks, err := Load(config.JWTConfig{SigningKeyID: "k1", Keys: []config.JWTKeyConfig{{ID: "k1", PrivateKeyFile: path}}})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if algs := ks.Algorithms(); len(algs) != 1 || algs[0] != "EdDSA" {
+		t.Fatalf("unexpected algorithms %v", algs)
+	}
+
+	hs, err := Load(config.JWTConfig{Secret: "secret"})
+	if err != nil || len(hs.JWKS().Keys) != 0 {
+		t.Fatalf("expected HS256 key set with an empty JWKS, got err=%v", err)
+	}
+	if _, err := Load(config.JWTConfig{}); err == nil {
+		t.Fatalf("expected error without secret or keys")
+	}
+}
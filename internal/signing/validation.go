@@ -0,0 +1,69 @@
+package signing
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DefaultIssuer is used for iss and aud when the config leaves them empty
+const DefaultIssuer = "go-flix-api"
+
+// Validation holds the claim checks applied to every token on top of the signature
+type Validation struct {
+	Issuer     string        // iss stamped on new tokens and required on incoming ones
+	Audience   string        // aud stamped on new tokens and required on incoming ones
+	Algorithms []string      // allowed alg values; empty means every algorithm of the configured keys
+	Leeway     time.Duration // tolerated clock skew for exp, nbf and iat
+}
+
+// SetValidation replaces the claim checks. Every allowed algorithm must belong to
+// a configured key, and the signing key's algorithm must be allowed.
+func (ks *KeySet) SetValidation(v Validation) error {
+	if v.Issuer == "" {
+		v.Issuer = DefaultIssuer
+	}
+	if v.Audience == "" {
+		v.Audience = DefaultIssuer
+	}
+	if v.Leeway < 0 {
+		return fmt.Errorf("leeway must not be negative")
+	}
+	keyAlgs := ks.keyAlgorithms()
+	if len(v.Algorithms) == 0 {
+		v.Algorithms = keyAlgs
+	}
+	for _, alg := range v.Algorithms {
+		if !slices.Contains(keyAlgs, alg) {
+			return fmt.Errorf("algorithm %q is allowed but no configured key uses it", alg)
+		}
+	}
+	if !slices.Contains(v.Algorithms, ks.signer.Method.Alg()) {
+		return fmt.Errorf("signing key algorithm %q is not in the allowed algorithms", ks.signer.Method.Alg())
+	}
+	ks.validation = v
+	return nil
+}
+
+// Stamp sets iss and aud on claims about to be signed so they pass Parse
+func (ks *KeySet) Stamp(claims *jwt.RegisteredClaims) {
+	claims.Issuer = ks.validation.Issuer
+	claims.Audience = jwt.ClaimStrings{ks.validation.Audience}
+}
+
+// Parse verifies tokenStr into claims: signature by kid, allowed algorithm,
+// issuer, audience, a required exp, and the configured leeway.
+// The returned error says why a token was rejected; log it, never send it to clients.
+func (ks *KeySet) Parse(tokenStr string, claims jwt.Claims) (*jwt.Token, error) {
+	v := ks.validation
+	return jwt.ParseWithClaims(tokenStr, claims, ks.Keyfunc,
+		jwt.WithValidMethods(v.Algorithms),
+		jwt.WithIssuer(v.Issuer),
+		jwt.WithAudience(v.Audience),
+		jwt.WithLeeway(v.Leeway),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+}
@@ -0,0 +1,105 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func stamped(ks *KeySet, mutate func(*jwt.RegisteredClaims)) string {
+	c := claims()
+	ks.Stamp(&c)
+	if mutate != nil {
+		mutate(&c)
+	}
+	token, _ := ks.Sign(c)
+	return token
+}
+
+func TestParseEnforcesValidation(t *testing.T) {
+	ks := NewHMACKeySet([]byte("test_secret"))
+	if err := ks.SetValidation(Validation{Issuer: "go-flix-api", Audience: "movies", Leeway: 30 * time.Second}); err != nil {
+		t.Fatalf("SetValidation: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(*jwt.RegisteredClaims)
+		want   error
+	}{
+		{"valid", nil, nil},
+		{"other audience", func(c *jwt.RegisteredClaims) { c.Audience = jwt.ClaimStrings{"billing"} }, jwt.ErrTokenInvalidAudience},
+		{"other issuer", func(c *jwt.RegisteredClaims) { c.Issuer = "someone-else" }, jwt.ErrTokenInvalidIssuer},
+		{"no exp", func(c *jwt.RegisteredClaims) { c.ExpiresAt = nil }, jwt.ErrTokenRequiredClaimMissing},
+		{"expired within leeway", func(c *jwt.RegisteredClaims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-10 * time.Second)) }, nil},
+		{"expired beyond leeway", func(c *jwt.RegisteredClaims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute)) }, jwt.ErrTokenExpired},
+		{"issued in the future", func(c *jwt.RegisteredClaims) { c.IssuedAt = jwt.NewNumericDate(time.Now().Add(time.Hour)) }, jwt.ErrTokenUsedBeforeIssued},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ks.Parse(stamped(ks, tt.mutate), &jwt.RegisteredClaims{})
+			if tt.want == nil && err != nil {
+				t.Fatalf("expected token to verify, got %v", err)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestParseRejectsAlgorithmOutsideAllowList(t *testing.T) {
+	_, edPriv, _ := ed25519.GenerateKey(rand.Reader)
+	edKey, err := ParsePrivateKeyPEM("ed", privatePEM(t, edPriv))
+	if err != nil {
+		t.Fatalf("parse Ed25519 key: %v", err)
+	}
+	legacy := &Key{ID: "legacy", Method: jwt.SigningMethodHS256, signingKey: []byte("test_secret"), verifyKey: []byte("test_secret")}
+	legacySet, _ := NewKeySet("legacy", legacy)
+	hsToken := stamped(legacySet, nil)
+
+	ks, err := NewKeySet("ed", edKey, legacy)
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+	if _, err := ks.Parse(hsToken, &jwt.RegisteredClaims{}); err != nil {
+		t.Fatalf("expected HS256 to be allowed by default, got %v", err)
+	}
+
+	if err := ks.SetValidation(Validation{Algorithms: []string{"HS256"}}); err == nil {
+		t.Fatal("expected allow list without the signing algorithm to be rejected")
+	}
+	// HS256 masih punya kunci, tetapi tidak lagi diizinkan
+	if err := ks.SetValidation(Validation{Algorithms: []string{"EdDSA"}}); err != nil {
+		t.Fatalf("SetValidation: %v", err)
+	}
+	if _, err := ks.Parse(hsToken, &jwt.RegisteredClaims{}); !errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+		t.Fatalf("expected disallowed alg to be rejected, got %v", err)
+	}
+	if _, err := ks.Parse(stamped(ks, nil), &jwt.RegisteredClaims{}); err != nil {
+		t.Fatalf("expected EdDSA token to verify, got %v", err)
+	}
+}
+
+func TestSetValidation(t *testing.T) {
+	ks := NewHMACKeySet([]byte("test_secret"))
+	if err := ks.SetValidation(Validation{Algorithms: []string{"RS256"}}); err == nil {
+		t.Fatal("expected algorithm without a matching key to be rejected")
+	}
+	if err := ks.SetValidation(Validation{Algorithms: []string{}}); err != nil {
+		t.Fatalf("expected empty allow list to mean the key algorithms, got %v", err)
+	}
+	if err := ks.SetValidation(Validation{Leeway: -time.Second}); err == nil {
+		t.Fatal("expected negative leeway to be rejected")
+	}
+	if err := ks.SetValidation(Validation{}); err != nil {
+		t.Fatalf("expected defaults to be accepted, got %v", err)
+	}
+	if ks.validation.Issuer != DefaultIssuer || ks.validation.Audience != DefaultIssuer {
+		t.Fatalf("expected default issuer and audience, got %+v", ks.validation)
+	}
+}
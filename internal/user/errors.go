@@ -0,0 +1,42 @@
+package user
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// Domain errors returned by Service. Handlers map them to status codes with errors.Is.
+var (
+	// ErrNotFound means no user has the requested ID
+	ErrNotFound = errors.New("user not found")
+	// ErrUsernameTaken means another account already uses the username (case-insensitive)
+	ErrUsernameTaken = errors.New("username already taken")
+	// ErrInvalidCredentials means the username does not exist or the password is wrong.
+	// Keduanya sengaja tidak dibedakan agar username tidak bisa ditebak.
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	// ErrValidation means the request is invalid.
+	// Request validation failures also wrap validation.Errors with the per-field details.
+	ErrValidation = errors.New("invalid user")
+	// ErrLastAdmin is returned when a change would leave the system without any admin
+	ErrLastAdmin = errors.New("cannot remove the last admin")
+)
+
+const pqUniqueViolation = "23505"
+
+// translateError maps sql.ErrNoRows and unique violations onto the domain errors above
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == pqUniqueViolation {
+		return fmt.Errorf("%w: %s", ErrUsernameTaken, pqErr.Detail)
+	}
+	return err
+}
@@ -0,0 +1,284 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-flix-api/internal/middleware"
+	"go-flix-api/internal/problem"
+	"go-flix-api/internal/validation"
+	"go-flix-api/models"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	service  *Service
+	throttle PasswordThrottle
+}
+
+// PasswordThrottle membatasi tebakan password lama di PUT /api/me/password.
+// Dipenuhi oleh auth.LoginThrottle; hitungannya sama dengan login, jadi sesi yang dicuri
+// tidak bisa dipakai untuk menebak password tanpa batas.
+type PasswordThrottle interface {
+	Check(ctx context.Context, username, ip string) (time.Duration, error)
+	RecordFailure(ctx context.Context, username, ip string) (locked bool, err error)
+	Succeed(ctx context.Context, username string) error
+}
+
+func NewHandler(service *Service, throttle PasswordThrottle) *Handler {
+	return &Handler{service: service, throttle: throttle}
+}
+
+// writeError maps service errors onto problem responses with the matching status code
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var verrs validation.Errors
+	switch {
+	case errors.As(err, &verrs):
+		problem.Validation(w, r, verrs)
+	case errors.Is(err, ErrValidation):
+		problem.Error(w, r, http.StatusUnprocessableEntity, problem.CodeValidationFailed, err.Error())
+	case errors.Is(err, ErrNotFound):
+		problem.Error(w, r, http.StatusNotFound, problem.CodeUserNotFound, "User not found")
+	case errors.Is(err, ErrUsernameTaken):
+		problem.Error(w, r, http.StatusConflict, problem.CodeUsernameTaken, "Username already taken")
+	case errors.Is(err, ErrLastAdmin):
+		problem.Error(w, r, http.StatusConflict, problem.CodeConflict, "Cannot demote or delete the last admin")
+	case errors.Is(err, ErrInvalidCredentials):
+		problem.Error(w, r, http.StatusUnauthorized, problem.CodeInvalidCredentials, "Current password is incorrect")
+	default:
+		problem.Internal(w, r, err)
+	}
+}
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// parsePage reads page and limit from the query string, applying defaults and bounds
+func parsePage(r *http.Request) (page, limit int, ok bool) {
+	page, limit = 1, defaultPageLimit
+	q := r.URL.Query()
+	if v := q.Get("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return 0, 0, false
+		}
+		page = n
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageLimit {
+			return 0, 0, false
+		}
+		limit = n
+	}
+	return page, limit, true
+}
+
+// @Summary Register a new account
+// @Description Create a read-only (viewer) account. Passwords must be 8 to 72 characters.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param account body models.RegisterRequest true "New account"
+// @Success 201 {object} models.User
+// @Failure 400 {object} problem.Problem
+// @Failure 409 {object} problem.Problem "Username already taken"
+// @Failure 422 {object} problem.Problem
+// @Router /api/register [post]
+func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
+	var req models.RegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "Invalid JSON format")
+		return
+	}
+	u, err := h.service.Register(r.Context(), req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(u)
+}
+
+// @Summary Change own password
+// @Description Change the password of the logged-in user. The current password is required.
+// @Tags auth
+// @Accept json
+// @Security BearerAuth
+// @Param body body models.ChangePasswordRequest true "Current and new password"
+// @Success 204 {object} nil
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem "Current password is incorrect"
+// @Failure 422 {object} problem.Problem
+// @Failure 429 {object} problem.Problem "Too many wrong passwords, see Retry-After"
+// @Router /api/me/password [put]
+func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	var req models.ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "Invalid JSON format")
+		return
+	}
+	ctx := r.Context()
+	principal := middleware.PrincipalFromContext(ctx)
+	username := principal.Username
+	ip := middleware.ClientIP(r)
+	if wait, err := h.throttle.Check(ctx, username, ip); err != nil {
+		problem.Internal(w, r, err)
+		return
+	} else if wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		problem.Error(w, r, http.StatusTooManyRequests, problem.CodeLoginThrottled, "Too many failed password attempts, try again later")
+		return
+	}
+
+	err := h.service.ChangePassword(ctx, principal, req)
+	if errors.Is(err, ErrInvalidCredentials) {
+		locked, ferr := h.throttle.RecordFailure(ctx, username, ip)
+		if ferr != nil {
+			problem.Internal(w, r, ferr)
+			return
+		}
+		// Format sama dengan securityEvent di package auth
+		slog.Warn("Security event", "event", "password_change_failed", "request_id", r.Header.Get(problem.RequestIDHeader),
+			"username", username, "ip", ip, "locked", locked)
+	} else if err == nil {
+		err = h.throttle.Succeed(ctx, username)
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary List users
+// @Description List all accounts ordered by username. Requires users:manage (admin).
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number (starts at 1)" default(1)
+// @Param limit query int false "Page size (max 100)" default(20)
+// @Success 200 {object} models.UserListResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Router /api/users [get]
+func (h *Handler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	page, limit, ok := parsePage(r)
+	if !ok {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeBadRequest, "page must be >= 1 and limit between 1 and 100")
+		return
+	}
+	resp, err := h.service.GetUsers(r.Context(), page, limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary Get a user
+// @Description Get one account by ID. Requires users:manage (admin).
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} models.User
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Router /api/users/{id} [get]
+func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) {
+	u, err := h.service.GetUser(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(u)
+}
+
+// @Summary Create a user
+// @Description Create an account with any role. Requires users:manage (admin).
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param user body models.CreateUserRequest true "New account"
+// @Success 201 {object} models.User
+// @Failure 400 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 409 {object} problem.Problem "Username already taken"
+// @Failure 422 {object} problem.Problem
+// @Router /api/users [post]
+func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "Invalid JSON format")
+		return
+	}
+	u, err := h.service.CreateUser(r.Context(), req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(u)
+}
+
+// @Summary Update a user
+// @Description Change the role of an account and/or reset its password. Requires users:manage (admin).
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param user body models.UpdateUserRequest true "Fields to change"
+// @Success 200 {object} models.User
+// @Failure 400 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem "Would demote the last admin"
+// @Failure 422 {object} problem.Problem
+// @Router /api/users/{id} [put]
+func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	var req models.UpdateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "Invalid JSON format")
+		return
+	}
+	u, err := h.service.UpdateUser(r.Context(), mux.Vars(r)["id"], req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(u)
+}
+
+// @Summary Delete a user
+// @Description Permanently delete an account. Requires users:manage (admin).
+// @Tags users
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 204 {object} nil
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem "Would delete the last admin"
+// @Router /api/users/{id} [delete]
+func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteUser(r.Context(), mux.Vars(r)["id"]); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,73 @@
+package user
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+
+	"go-flix-api/internal/middleware"
+	"go-flix-api/internal/rbac"
+)
+
+type fakeThrottle struct {
+	wait      time.Duration
+	failures  int
+	succeeded int
+}
+
+func (f *fakeThrottle) Check(ctx context.Context, username, ip string) (time.Duration, error) {
+	return f.wait, nil
+}
+
+func (f *fakeThrottle) RecordFailure(ctx context.Context, username, ip string) (bool, error) {
+	f.failures++
+	return false, nil
+}
+
+func (f *fakeThrottle) Succeed(ctx context.Context, username string) error {
+	f.succeeded++
+	return nil
+}
+
+func changePassword(h *Handler, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPut, "/api/me/password", strings.NewReader(body))
+	req = req.WithContext(middleware.WithPrincipal(req.Context(), middleware.Principal{Username: "user1", Role: rbac.RoleViewer}))
+	rec := httptest.NewRecorder()
+	h.ChangePassword(rec, req)
+	return rec
+}
+
+func TestChangePasswordIsThrottled(t *testing.T) {
+	svc, mock := newMockService(t)
+	throttle := &fakeThrottle{}
+	h := NewHandler(svc, throttle)
+	hash, _ := HashPassword("password123")
+	findQuery := regexp.QuoteMeta("FROM users WHERE LOWER(username) = LOWER($1)")
+	body := `{"current_password":"wrong-guess","new_password":"new-password-456"}`
+
+	mock.ExpectQuery(findQuery).WithArgs("user1").
+		WillReturnRows(sqlmock.NewRows(userCols).AddRow(uuid.New(), "user1", hash, rbac.RoleViewer, time.Now(), time.Now()))
+	if rec := changePassword(h, body); rec.Code != http.StatusUnauthorized || throttle.failures != 1 {
+		t.Fatalf("expected 401 counted as failure, got %d failures=%d", rec.Code, throttle.failures)
+	}
+
+	// Terkunci: ditolak sebelum password diperiksa
+	throttle.wait = 30 * time.Second
+	rec := changePassword(h, body)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "30" {
+		t.Fatalf("expected 429 with Retry-After 30, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if throttle.failures != 1 || throttle.succeeded != 0 {
+		t.Fatalf("throttled request must not touch the counters: %+v", throttle)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package user
+
+import (
+	"fmt"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// bcryptCost is the work factor for new hashes; tests lower it to keep them fast
+var bcryptCost = 12
+
+// maxPasswordBytes is bcrypt's input limit; longer passwords are rejected instead of truncated
+const maxPasswordBytes = 72
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// HashPassword returns the bcrypt hash of password
+func HashPassword(password string) (string, error) {
+	if len(password) > maxPasswordBytes {
+		return "", fmt.Errorf("%w: password must be at most %d bytes", ErrValidation, maxPasswordBytes)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// checkPassword reports whether password matches hash.
+// bcrypt membandingkan hasil hash dengan constant-time compare.
+func checkPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// burnPasswordCheck runs a bcrypt comparison against a throwaway hash so that
+// a login for an unknown username takes as long as one with a wrong password.
+func burnPasswordCheck(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("go-flix-dummy-password"), bcryptCost)
+	})
+	bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
+
+// isBcryptHash reports whether s looks like a bcrypt hash, used when importing password_hash from config
+func isBcryptHash(s string) bool {
+	_, err := bcrypt.Cost([]byte(s))
+	return err == nil
+}
@@ -0,0 +1,159 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+
+	"go-flix-api/internal/rbac"
+	"go-flix-api/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(db *sqlx.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const userColumns = `id, username, password_hash, role, created_at, updated_at, tokens_valid_after`
+
+// FindByUsername looks a user up case-insensitively
+func (r *Repository) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	var u models.User
+	query := `SELECT ` + userColumns + ` FROM users WHERE LOWER(username) = LOWER($1)`
+	if err := r.db.GetContext(ctx, &u, query, username); err != nil {
+		return nil, translateError(err)
+	}
+	return &u, nil
+}
+
+func (r *Repository) FindByID(ctx context.Context, id string) (*models.User, error) {
+	var u models.User
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
+	if err := r.db.GetContext(ctx, &u, query, id); err != nil {
+		return nil, translateError(err)
+	}
+	return &u, nil
+}
+
+// FindAll returns one page of users ordered by username, and the total user count
+func (r *Repository) FindAll(ctx context.Context, page, limit int) ([]models.User, int, error) {
+	total, err := r.Count(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	query := `SELECT ` + userColumns + ` FROM users ORDER BY username LIMIT $1 OFFSET $2`
+	users := []models.User{}
+	if err := r.db.SelectContext(ctx, &users, query, limit, (page-1)*limit); err != nil {
+		return nil, 0, translateError(err)
+	}
+	return users, total, nil
+}
+
+func (r *Repository) Count(ctx context.Context) (int, error) {
+	var total int
+	err := r.db.GetContext(ctx, &total, `SELECT COUNT(*) FROM users`)
+	return total, translateError(err)
+}
+
+const insertUser = `INSERT INTO users (id, username, password_hash, role, created_at, updated_at, tokens_valid_after)
+	VALUES (:id, :username, :password_hash, :role, :created_at, :updated_at, :tokens_valid_after)`
+
+// updateUser memajukan tokens_valid_after bila role atau password benar-benar berubah.
+// Dibandingkan dengan nilai baris saat UPDATE, bukan hasil baca sebelumnya, jadi
+// perubahan yang terjadi bersamaan tetap mencabut access token lama.
+const updateUser = `UPDATE users SET role = :role, password_hash = :password_hash, updated_at = :updated_at,
+	tokens_valid_after = CASE WHEN role <> :role OR password_hash <> :password_hash
+		THEN :updated_at ELSE tokens_valid_after END
+	WHERE id = :id`
+
+const deleteUser = `DELETE FROM users WHERE id = $1`
+
+func (r *Repository) Create(ctx context.Context, u models.User) error {
+	_, err := r.db.NamedExecContext(ctx, insertUser, u)
+	return translateError(err)
+}
+
+// CreateAll inserts users in a single transaction: either all are created or none
+func (r *Repository) CreateAll(ctx context.Context, users []models.User) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return translateError(err)
+	}
+	defer tx.Rollback()
+	for _, u := range users {
+		if _, err := tx.NamedExecContext(ctx, insertUser, u); err != nil {
+			return translateError(err)
+		}
+	}
+	return translateError(tx.Commit())
+}
+
+// Update writes the role and password hash of an existing user
+func (r *Repository) Update(ctx context.Context, u models.User) error {
+	result, err := r.db.NamedExecContext(ctx, updateUser, u)
+	if err != nil {
+		return translateError(err)
+	}
+	return requireRow(result.RowsAffected())
+}
+
+// UpdateRetainingAdmin is Update for a change that sets a non-admin role on u.
+// Whether that demotes an admin is decided from the row locked in the transaction,
+// so a concurrent promotion cannot slip past the check. Returns ErrLastAdmin if u is
+// the only admin left.
+func (r *Repository) UpdateRetainingAdmin(ctx context.Context, u models.User) error {
+	return r.retainAdmin(ctx, u.ID.String(), func(tx *sqlx.Tx) (sql.Result, error) {
+		return tx.NamedExecContext(ctx, updateUser, u)
+	})
+}
+
+// Delete removes a user. Returns ErrLastAdmin if id is the only admin left,
+// checked against the admin rows locked in the same transaction.
+func (r *Repository) Delete(ctx context.Context, id string) error {
+	return r.retainAdmin(ctx, id, func(tx *sqlx.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, deleteUser, id)
+	})
+}
+
+// retainAdmin runs write in a transaction that first locks every admin row, and refuses
+// with ErrLastAdmin when id is the only admin. Pengecekan dan penulisan ada di transaksi
+// yang sama, jadi dua admin yang saling menurunkan/menghapus bersamaan tidak bisa
+// sama-sama lolos: yang kedua menunggu lock lalu melihat hanya satu admin tersisa.
+func (r *Repository) retainAdmin(ctx context.Context, id string, write func(tx *sqlx.Tx) (sql.Result, error)) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return translateError(err)
+	}
+	defer tx.Rollback()
+	var admins []string
+	if err := tx.SelectContext(ctx, &admins, `SELECT id FROM users WHERE role = $1 FOR UPDATE`, rbac.RoleAdmin); err != nil {
+		return translateError(err)
+	}
+	if len(admins) < 2 && slices.Contains(admins, id) {
+		return ErrLastAdmin
+	}
+	result, err := write(tx)
+	if err != nil {
+		return translateError(err)
+	}
+	if err := requireRow(result.RowsAffected()); err != nil {
+		return err
+	}
+	return translateError(tx.Commit())
+}
+
+// requireRow turns "zero rows affected" into ErrNotFound
+func requireRow(n int64, err error) error {
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
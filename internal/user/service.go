@@ -0,0 +1,330 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go-flix-api/config"
+	"go-flix-api/internal/middleware"
+	"go-flix-api/internal/rbac"
+	"go-flix-api/internal/validation"
+	"go-flix-api/models"
+
+	"github.com/google/uuid"
+)
+
+type Service struct {
+	repo     *Repository
+	sessions SessionRevoker
+	apiKeys  APIKeyRevoker
+}
+
+// SessionRevoker mengakhiri sesi login seorang user. Dipenuhi oleh auth.SessionRevoker.
+type SessionRevoker interface {
+	// RevokeUser mencabut semua refresh token family milik username
+	RevokeUser(ctx context.Context, username string) error
+	RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error
+}
+
+// APIKeyRevoker mencabut API key yang dibuat oleh akun yang dihapus. Dipenuhi oleh apikey.Service.
+type APIKeyRevoker interface {
+	RevokeCreatedBy(ctx context.Context, username string) (int64, error)
+}
+
+func NewService(repo *Repository, sessions SessionRevoker, apiKeys APIKeyRevoker) *Service {
+	return &Service{repo: repo, sessions: sessions, apiKeys: apiKeys}
+}
+
+// checkID rejects IDs that are not UUIDs with ErrNotFound, since no user can have them
+func checkID(id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// validate runs the request validation, wrapping failures in ErrValidation
+func validate(req interface{}) error {
+	if err := validation.Struct(req); err != nil {
+		return fmt.Errorf("%w: %w", ErrValidation, err)
+	}
+	return nil
+}
+
+// Authenticate returns the user if username and password match.
+// Unknown usernames still pay for a bcrypt comparison so response time does not reveal which usernames exist.
+func (s *Service) Authenticate(ctx context.Context, username, password string) (*models.User, error) {
+	u, err := s.repo.FindByUsername(ctx, username)
+	if errors.Is(err, ErrNotFound) {
+		burnPasswordCheck(password)
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !checkPassword(u.PasswordHash, password) {
+		return nil, ErrInvalidCredentials
+	}
+	return u, nil
+}
+
+// GetByUsername returns the account for username, or ErrNotFound
+func (s *Service) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	return s.repo.FindByUsername(ctx, username)
+}
+
+// Register creates a read-only (viewer) account for self sign-up
+func (s *Service) Register(ctx context.Context, req models.RegisterRequest) (*models.User, error) {
+	if err := validate(req); err != nil {
+		return nil, err
+	}
+	return s.create(ctx, req.Username, req.Password, rbac.RoleViewer)
+}
+
+// CreateUser creates an account with any role (admin only)
+func (s *Service) CreateUser(ctx context.Context, req models.CreateUserRequest) (*models.User, error) {
+	if err := validate(req); err != nil {
+		return nil, err
+	}
+	role := req.Role
+	if role == "" {
+		role = rbac.RoleViewer
+	}
+	return s.create(ctx, req.Username, req.Password, role)
+}
+
+func (s *Service) create(ctx context.Context, username, password, role string) (*models.User, error) {
+	hash, err := HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	u := models.User{
+		ID:               uuid.New(),
+		Username:         username,
+		PasswordHash:     hash,
+		Role:             role,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+		TokensValidAfter: &now,
+	}
+	if err := s.repo.Create(ctx, u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// ChangePassword sets a new password for the principal after verifying the current one.
+// Every session of the account ends, including the access token used for this request.
+func (s *Service) ChangePassword(ctx context.Context, p middleware.Principal, req models.ChangePasswordRequest) error {
+	if err := validate(req); err != nil {
+		return err
+	}
+	u, err := s.Authenticate(ctx, p.Username, req.CurrentPassword)
+	if err != nil {
+		return err
+	}
+	hash, err := HashPassword(req.NewPassword)
+	if err != nil {
+		return err
+	}
+	u.PasswordHash = hash
+	u.UpdatedAt = time.Now()
+	if err := s.repo.Update(ctx, *u); err != nil {
+		return err
+	}
+	if err := s.sessions.RevokeUser(ctx, u.Username); err != nil {
+		return err
+	}
+	if p.TokenID != "" {
+		return s.sessions.RevokeAccessToken(ctx, p.TokenID, p.ExpiresAt)
+	}
+	return nil
+}
+
+// TokensValidAfter returns the cutoff for access tokens of username; tokens issued
+// before it are refused by the authenticator. Tokens of a deleted account are always refused.
+func (s *Service) TokensValidAfter(ctx context.Context, username string) (time.Time, error) {
+	u, err := s.repo.FindByUsername(ctx, username)
+	if errors.Is(err, ErrNotFound) {
+		return time.Time{}, fmt.Errorf("%w: account no longer exists", middleware.ErrRevokedCredentials)
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	if u.TokensValidAfter == nil {
+		return time.Time{}, nil
+	}
+	return *u.TokensValidAfter, nil
+}
+
+// GetUsers returns one page of users
+func (s *Service) GetUsers(ctx context.Context, page, limit int) (*models.UserListResponse, error) {
+	users, total, err := s.repo.FindAll(ctx, page, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &models.UserListResponse{Data: users, Total: total, Page: page, Limit: limit}, nil
+}
+
+func (s *Service) GetUser(ctx context.Context, id string) (*models.User, error) {
+	if err := checkID(id); err != nil {
+		return nil, err
+	}
+	return s.repo.FindByID(ctx, id)
+}
+
+// UpdateUser changes the role and/or resets the password of a user (admin only).
+// Either change ends every session of the user, including access tokens already issued.
+// The last admin cannot be demoted, so user management is never locked out.
+func (s *Service) UpdateUser(ctx context.Context, id string, req models.UpdateUserRequest) (*models.User, error) {
+	if err := validate(req); err != nil {
+		return nil, err
+	}
+	u, err := s.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	roleChanged := req.Role != nil && *req.Role != u.Role
+	if req.Role != nil {
+		u.Role = *req.Role
+	}
+	if req.Password != nil {
+		if u.PasswordHash, err = HashPassword(*req.Password); err != nil {
+			return nil, err
+		}
+	}
+	u.UpdatedAt = time.Now()
+	// Apakah ini menurunkan admin diputuskan dari baris yang dikunci, bukan dari u yang
+	// dibaca di luar transaksi: user bisa saja dipromosikan di antaranya
+	if req.Role != nil && *req.Role != rbac.RoleAdmin {
+		err = s.repo.UpdateRetainingAdmin(ctx, *u)
+	} else {
+		err = s.repo.Update(ctx, *u)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if roleChanged || req.Password != nil {
+		if err := s.sessions.RevokeUser(ctx, u.Username); err != nil {
+			return nil, err
+		}
+	}
+	return u, nil
+}
+
+// DeleteUser removes an account permanently (admin only), together with its sessions
+// and the API keys it created. Access tokens of a deleted account are refused by
+// TokensValidAfter. The last admin cannot be deleted.
+func (s *Service) DeleteUser(ctx context.Context, id string) error {
+	u, err := s.GetUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	if err := s.sessions.RevokeUser(ctx, u.Username); err != nil {
+		return err
+	}
+	revoked, err := s.apiKeys.RevokeCreatedBy(ctx, u.Username)
+	if err != nil {
+		return err
+	}
+	if revoked > 0 {
+		slog.Info("API key milik user yang dihapus dicabut", "username", u.Username, "count", revoked)
+	}
+	return nil
+}
+
+// ImportUsers copies the users from config.yml into an empty users table.
+// It runs on every boot but does nothing once any account exists, so config edits
+// never overwrite passwords changed through the API. Returns the number imported.
+func (s *Service) ImportUsers(ctx context.Context, users []config.User) (int, error) {
+	count, err := s.repo.Count(ctx)
+	if err != nil || count > 0 {
+		return 0, err
+	}
+	now := time.Now()
+	imported := make([]models.User, 0, len(users))
+	for _, cu := range users {
+		u, err := userFromConfig(cu, now)
+		if err != nil {
+			return 0, err
+		}
+		imported = append(imported, u)
+	}
+	// Satu transaksi: import setengah jalan akan membuat boot berikutnya melewatkan sisanya
+	if err := s.repo.CreateAll(ctx, imported); err != nil {
+		return 0, err
+	}
+	return len(imported), nil
+}
+
+// ImportNewUsers creates the config users whose username does not exist yet.
+// Dipakai saat config di-reload: akun yang sudah ada tidak pernah disentuh,
+// termasuk password dan role yang diubah lewat API. Returns the number created.
+func (s *Service) ImportNewUsers(ctx context.Context, users []config.User) (int, error) {
+	now := time.Now()
+	var created []models.User
+	for _, cu := range users {
+		_, err := s.repo.FindByUsername(ctx, cu.Username)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return 0, err
+		}
+		u, err := userFromConfig(cu, now)
+		if err != nil {
+			return 0, err
+		}
+		created = append(created, u)
+	}
+	if len(created) == 0 {
+		return 0, nil
+	}
+	if err := s.repo.CreateAll(ctx, created); err != nil {
+		return 0, err
+	}
+	return len(created), nil
+}
+
+// userFromConfig converts one users entry from config.yml, hashing a legacy plaintext password
+func userFromConfig(cu config.User, now time.Time) (models.User, error) {
+	hash := cu.PasswordHash
+	switch {
+	case hash != "":
+		if !isBcryptHash(hash) {
+			return models.User{}, fmt.Errorf("user %q: password_hash is not a bcrypt hash", cu.Username)
+		}
+	case cu.Password != "":
+		slog.Warn("User di config memakai password plaintext, ganti dengan password_hash", "username", cu.Username)
+		var err error
+		if hash, err = HashPassword(cu.Password); err != nil {
+			return models.User{}, fmt.Errorf("user %q: %w", cu.Username, err)
+		}
+	default:
+		return models.User{}, fmt.Errorf("user %q: password_hash is required", cu.Username)
+	}
+	// User di config tanpa role dulu boleh menulis film, jadi dipetakan ke editor
+	role := cu.Role
+	if role == "" {
+		role = rbac.RoleEditor
+	}
+	if !rbac.Valid(role) {
+		return models.User{}, fmt.Errorf("user %q: unknown role %q", cu.Username, role)
+	}
+	return models.User{
+		ID:               uuid.New(),
+		Username:         cu.Username,
+		PasswordHash:     hash,
+		Role:             role,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+		TokensValidAfter: &now,
+	}, nil
+}
@@ -0,0 +1,322 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"golang.org/x/crypto/bcrypt"
+
+	"go-flix-api/config"
+	"go-flix-api/internal/middleware"
+	"go-flix-api/internal/rbac"
+	"go-flix-api/internal/signing"
+	"go-flix-api/models"
+)
+
+func init() {
+	// Hash dengan cost minimum agar test tidak lambat
+	bcryptCost = bcrypt.MinCost
+}
+
+var userCols = []string{"id", "username", "password_hash", "role", "created_at", "updated_at"}
+
+// fakeRevoker mencatat sesi, access token dan API key yang dicabut
+type fakeRevoker struct {
+	users     []string
+	tokens    []string
+	keyOwners []string
+}
+
+func (f *fakeRevoker) RevokeUser(ctx context.Context, username string) error {
+	f.users = append(f.users, username)
+	return nil
+}
+
+func (f *fakeRevoker) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	f.tokens = append(f.tokens, jti)
+	return nil
+}
+
+func (f *fakeRevoker) RevokeCreatedBy(ctx context.Context, username string) (int64, error) {
+	f.keyOwners = append(f.keyOwners, username)
+	return 0, nil
+}
+
+func newMockService(t *testing.T) (*Service, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	revoker := &fakeRevoker{}
+	return NewService(NewRepository(sqlx.NewDb(db, "sqlmock")), revoker, revoker), mock
+}
+
+func TestAuthenticate(t *testing.T) {
+	svc, mock := newMockService(t)
+	hash, _ := HashPassword("password123")
+	findQuery := regexp.QuoteMeta("FROM users WHERE LOWER(username) = LOWER($1)")
+
+	mock.ExpectQuery(findQuery).WithArgs("user1").
+		WillReturnRows(sqlmock.NewRows(userCols).AddRow(uuid.New(), "user1", hash, rbac.RoleAdmin, time.Now(), time.Now()))
+	u, err := svc.Authenticate(context.Background(), "user1", "password123")
+	if err != nil || u.Role != rbac.RoleAdmin {
+		t.Fatalf("expected authenticated admin, got %+v err=%v", u, err)
+	}
+
+	mock.ExpectQuery(findQuery).WithArgs("user1").
+		WillReturnRows(sqlmock.NewRows(userCols).AddRow(uuid.New(), "user1", hash, rbac.RoleEditor, time.Now(), time.Now()))
+	if _, err := svc.Authenticate(context.Background(), "user1", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials for wrong password, got %v", err)
+	}
+
+	mock.ExpectQuery(findQuery).WithArgs("nobody").
+		WillReturnRows(sqlmock.NewRows(userCols))
+	if _, err := svc.Authenticate(context.Background(), "nobody", "password123"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials for unknown user, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestImportUsersOnlyIntoEmptyTable(t *testing.T) {
+	svc, mock := newMockService(t)
+	countQuery := regexp.QuoteMeta("SELECT COUNT(*) FROM users")
+	hash, _ := HashPassword("secret123")
+	users := []config.User{
+		{Username: "boss", PasswordHash: hash, Role: rbac.RoleAdmin},
+		{Username: "legacy", Password: "password123"},
+	}
+
+	mock.ExpectQuery(countQuery).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO users")).
+		WithArgs(sqlmock.AnyArg(), "boss", hash, rbac.RoleAdmin, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO users")).
+		WithArgs(sqlmock.AnyArg(), "legacy", sqlmock.AnyArg(), rbac.RoleEditor, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if n, err := svc.ImportUsers(context.Background(), users); err != nil || n != 2 {
+		t.Fatalf("expected 2 users imported, got n=%d err=%v", n, err)
+	}
+
+	// Tabel sudah berisi: config diabaikan
+	mock.ExpectQuery(countQuery).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	if n, err := svc.ImportUsers(context.Background(), users); err != nil || n != 0 {
+		t.Fatalf("expected import skipped, got n=%d err=%v", n, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestImportNewUsersSkipsExistingAccounts(t *testing.T) {
+	svc, mock := newMockService(t)
+	findQuery := regexp.QuoteMeta("FROM users WHERE LOWER(username) = LOWER($1)")
+	hash, _ := HashPassword("secret123")
+	users := []config.User{
+		{Username: "boss", PasswordHash: hash, Role: rbac.RoleAdmin},
+		{Username: "batch", PasswordHash: hash, Role: rbac.RoleViewer},
+	}
+
+	mock.ExpectQuery(findQuery).WithArgs("boss").
+		WillReturnRows(sqlmock.NewRows(userCols).AddRow(uuid.New(), "boss", "changed", rbac.RoleEditor, time.Now(), time.Now()))
+	mock.ExpectQuery(findQuery).WithArgs("batch").WillReturnError(sql.ErrNoRows)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO users")).
+		WithArgs(sqlmock.AnyArg(), "batch", hash, rbac.RoleViewer, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if n, err := svc.ImportNewUsers(context.Background(), users); err != nil || n != 1 {
+		t.Fatalf("expected only the new user created, got n=%d err=%v", n, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestImportUsersRejectsInvalidHash(t *testing.T) {
+	svc, mock := newMockService(t)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users")).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	_, err := svc.ImportUsers(context.Background(), []config.User{{Username: "boss", PasswordHash: "plaintext"}})
+	if err == nil {
+		t.Fatalf("expected error for non-bcrypt password_hash")
+	}
+}
+
+func TestDemoteLastAdmin(t *testing.T) {
+	svc, mock := newMockService(t)
+	id := uuid.New()
+	mock.ExpectQuery(regexp.QuoteMeta("FROM users WHERE id = $1")).WithArgs(id.String()).
+		WillReturnRows(sqlmock.NewRows(userCols).AddRow(id, "boss", "x", rbac.RoleAdmin, time.Now(), time.Now()))
+	// Baris admin dikunci di transaksi yang sama dengan UPDATE; satu-satunya admin tidak boleh turun
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM users WHERE role = $1 FOR UPDATE")).WithArgs(rbac.RoleAdmin).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id.String()))
+	mock.ExpectRollback()
+
+	role := rbac.RoleEditor
+	_, err := svc.UpdateUser(context.Background(), id.String(), models.UpdateUserRequest{Role: &role})
+	if !errors.Is(err, ErrLastAdmin) {
+		t.Fatalf("expected ErrLastAdmin, got %v", err)
+	}
+	if revoked := svc.sessions.(*fakeRevoker).users; len(revoked) != 0 {
+		t.Fatalf("refused demotion must not revoke sessions, got %v", revoked)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestDemoteAdminWithAnotherAdmin(t *testing.T) {
+	svc, mock := newMockService(t)
+	id := uuid.New()
+	mock.ExpectQuery(regexp.QuoteMeta("FROM users WHERE id = $1")).WithArgs(id.String()).
+		WillReturnRows(sqlmock.NewRows(userCols).AddRow(id, "boss", "x", rbac.RoleAdmin, time.Now(), time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM users WHERE role = $1 FOR UPDATE")).WithArgs(rbac.RoleAdmin).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id.String()).AddRow(uuid.NewString()))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE users SET role")).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	role := rbac.RoleEditor
+	if _, err := svc.UpdateUser(context.Background(), id.String(), models.UpdateUserRequest{Role: &role}); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	if revoked := svc.sessions.(*fakeRevoker).users; len(revoked) != 1 || revoked[0] != "boss" {
+		t.Fatalf("expected sessions of boss revoked, got %v", revoked)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestChangePasswordRevokesSessions(t *testing.T) {
+	svc, mock := newMockService(t)
+	hash, _ := HashPassword("password123")
+	mock.ExpectQuery(regexp.QuoteMeta("FROM users WHERE LOWER(username) = LOWER($1)")).WithArgs("user1").
+		WillReturnRows(sqlmock.NewRows(userCols).AddRow(uuid.New(), "User1", hash, rbac.RoleViewer, time.Now(), time.Now()))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE users SET role")).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	p := middleware.Principal{Username: "user1", TokenID: "jti-1", ExpiresAt: time.Now().Add(time.Hour)}
+	req := models.ChangePasswordRequest{CurrentPassword: "password123", NewPassword: "new-password-456"}
+	if err := svc.ChangePassword(context.Background(), p, req); err != nil {
+		t.Fatalf("ChangePassword: %v", err)
+	}
+	revoker := svc.sessions.(*fakeRevoker)
+	if len(revoker.users) != 1 || revoker.users[0] != "User1" {
+		t.Fatalf("expected every refresh family of User1 revoked, got %v", revoker.users)
+	}
+	if len(revoker.tokens) != 1 || revoker.tokens[0] != "jti-1" {
+		t.Fatalf("expected the current access token revoked, got %v", revoker.tokens)
+	}
+}
+
+func TestDeleteUserRevokesSessionsAndAPIKeys(t *testing.T) {
+	svc, mock := newMockService(t)
+	id := uuid.New()
+	mock.ExpectQuery(regexp.QuoteMeta("FROM users WHERE id = $1")).WithArgs(id.String()).
+		WillReturnRows(sqlmock.NewRows(userCols).AddRow(id, "editor1", "x", rbac.RoleEditor, time.Now(), time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM users WHERE role = $1 FOR UPDATE")).WithArgs(rbac.RoleAdmin).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(uuid.NewString()))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM users WHERE id = $1")).WithArgs(id.String()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := svc.DeleteUser(context.Background(), id.String()); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	revoker := svc.sessions.(*fakeRevoker)
+	if len(revoker.users) != 1 || len(revoker.keyOwners) != 1 || revoker.keyOwners[0] != "editor1" {
+		t.Fatalf("expected sessions and API keys of editor1 revoked, got %+v", revoker)
+	}
+}
+
+func TestRegisterTakenUsername(t *testing.T) {
+	svc, mock := newMockService(t)
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO users")).
+		WillReturnError(&pq.Error{Code: pqUniqueViolation})
+	_, err := svc.Register(context.Background(), models.RegisterRequest{Username: "user1", Password: "password123"})
+	if !errors.Is(err, ErrUsernameTaken) {
+		t.Fatalf("expected ErrUsernameTaken, got %v", err)
+	}
+}
+
+func TestDemotedAdminTokenIsRefused(t *testing.T) {
+	svc, mock := newMockService(t)
+	keys := signing.NewHMACKeySet([]byte("test_secret"))
+	id := uuid.New()
+	created := time.Now().Add(-time.Hour)
+	claims := signing.Claims{Username: "boss", Role: rbac.RoleAdmin, RegisteredClaims: jwt.RegisteredClaims{
+		ID:        "jti-boss",
+		IssuedAt:  jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}}
+	keys.Stamp(&claims.RegisteredClaims)
+	token, err := keys.Sign(claims)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	h := middleware.NewAuthenticator(middleware.BearerJWT(keys, nil, svc)).Middleware(
+		middleware.RequirePermission(rbac.UsersManage)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	manageUsers := func(cutoff time.Time) int {
+		mock.ExpectQuery(regexp.QuoteMeta("FROM users WHERE LOWER(username) = LOWER($1)")).WithArgs("boss").
+			WillReturnRows(sqlmock.NewRows(append(userCols, "tokens_valid_after")).
+				AddRow(id, "boss", "x", rbac.RoleEditor, created, time.Now(), cutoff))
+		req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := manageUsers(created); code != http.StatusOK {
+		t.Fatalf("expected admin token accepted before the demotion, got %d", code)
+	}
+
+	// Penurunan role memajukan tokens_valid_after di UPDATE yang sama
+	mock.ExpectQuery(regexp.QuoteMeta("FROM users WHERE id = $1")).WithArgs(id.String()).
+		WillReturnRows(sqlmock.NewRows(userCols).AddRow(id, "boss", "x", rbac.RoleAdmin, created, created))
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM users WHERE role = $1 FOR UPDATE")).WithArgs(rbac.RoleAdmin).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id.String()).AddRow(uuid.NewString()))
+	mock.ExpectExec(regexp.QuoteMeta("tokens_valid_after = CASE WHEN role <>")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	role := rbac.RoleEditor
+	if _, err := svc.UpdateUser(context.Background(), id.String(), models.UpdateUserRequest{Role: &role}); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+
+	if code := manageUsers(time.Now()); code != http.StatusUnauthorized {
+		t.Fatalf("expected token issued before the demotion to be refused, got %d", code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestTokensValidAfterDeletedAccount(t *testing.T) {
+	svc, mock := newMockService(t)
+	mock.ExpectQuery(regexp.QuoteMeta("FROM users WHERE LOWER(username) = LOWER($1)")).WithArgs("gone").
+		WillReturnRows(sqlmock.NewRows(userCols))
+	if _, err := svc.TokensValidAfter(context.Background(), "gone"); !errors.Is(err, middleware.ErrRevokedCredentials) {
+		t.Fatalf("expected tokens of a deleted account to be revoked, got %v", err)
+	}
+}
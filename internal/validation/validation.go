@@ -0,0 +1,127 @@
+// Package validation evaluates the `validate` struct tags on request models
+// and turns failures into per-field errors that handlers can return to clients.
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError describes one rule that one request field failed
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Errors is returned by Struct when one or more fields are invalid
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	parts := make([]string, len(e))
+	for i, fe := range e {
+		parts[i] = fe.Field + " " + fe.Message
+	}
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	// Laporkan nama field sesuai JSON (judul, tahun_rilis), bukan nama field Go
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	v.RegisterValidation("notblank", notBlank)
+	v.RegisterValidation("maxyear", maxYear)
+	v.RegisterValidation("username", username)
+	return v
+}
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// username only allows letters, digits, dot, dash and underscore,
+// so usernames stay safe to show in audit columns and URLs
+func username(fl validator.FieldLevel) bool {
+	return usernamePattern.MatchString(fl.Field().String())
+}
+
+// notBlank rejects strings that are empty or only whitespace
+func notBlank(fl validator.FieldLevel) bool {
+	return strings.TrimSpace(fl.Field().String()) != ""
+}
+
+// maxYear rejects years later than the current year plus the tag parameter,
+// e.g. maxyear=5 still allows announced films up to five years ahead
+func maxYear(fl validator.FieldLevel) bool {
+	ahead, err := strconv.Atoi(fl.Param())
+	if err != nil {
+		return false
+	}
+	return fl.Field().Int() <= int64(time.Now().Year()+ahead)
+}
+
+// Struct validates s against its `validate` tags.
+// It returns Errors when fields are invalid, or another error if s cannot be validated at all.
+func Struct(s interface{}) error {
+	err := validate.Struct(s)
+	if err == nil {
+		return nil
+	}
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+	out := make(Errors, len(verrs))
+	for i, fe := range verrs {
+		out[i] = FieldError{Field: fe.Field(), Rule: fe.Tag(), Message: message(fe)}
+	}
+	return out
+}
+
+func message(fe validator.FieldError) string {
+	isString := fe.Kind() == reflect.String
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "notblank":
+		return "must not be blank"
+	case "min":
+		if isString {
+			return fmt.Sprintf("must be at least %s characters", fe.Param())
+		}
+		if fe.Kind() == reflect.Slice {
+			return fmt.Sprintf("must contain at least %s items", fe.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max":
+		if isString {
+			return fmt.Sprintf("must be at most %s characters", fe.Param())
+		}
+		if fe.Kind() == reflect.Slice {
+			return fmt.Sprintf("must contain at most %s items", fe.Param())
+		}
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", strings.ReplaceAll(fe.Param(), " ", ", "))
+	case "username":
+		return "may only contain letters, digits, '.', '-' and '_'"
+	case "maxyear":
+		ahead, _ := strconv.Atoi(fe.Param())
+		return fmt.Sprintf("must not be later than %d", time.Now().Year()+ahead)
+	default:
+		return fmt.Sprintf("failed the %q rule", fe.Tag())
+	}
+}
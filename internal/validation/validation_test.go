@@ -0,0 +1,86 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go-flix-api/models"
+)
+
+func fieldsOf(t *testing.T, err error) map[string]string {
+	t.Helper()
+	var verrs Errors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("expected validation.Errors, got %v", err)
+	}
+	fields := map[string]string{}
+	for _, fe := range verrs {
+		fields[fe.Field] = fe.Rule
+	}
+	return fields
+}
+
+func TestCreateMovieRequestRules(t *testing.T) {
+	valid := models.CreateMovieRequest{
+		Judul: "Inception", Genre: "Sci-Fi", TahunRilis: 2010, Sutradara: "Christopher Nolan",
+		Pemeran: []string{"Leonardo DiCaprio"},
+	}
+	if err := Struct(valid); err != nil {
+		t.Fatalf("expected valid request, got %v", err)
+	}
+
+	invalid := models.CreateMovieRequest{
+		Judul:      "   ",
+		Genre:      strings.Repeat("g", 101),
+		TahunRilis: time.Now().Year() + 6,
+		Pemeran:    []string{"Leonardo DiCaprio", ""},
+	}
+	fields := fieldsOf(t, Struct(invalid))
+	want := map[string]string{
+		"judul":       "notblank",
+		"genre":       "max",
+		"tahun_rilis": "maxyear",
+		"sutradara":   "required",
+		"pemeran[1]":  "notblank",
+	}
+	for field, rule := range want {
+		if fields[field] != rule {
+			t.Fatalf("expected %s to fail %q, got %v", field, rule, fields)
+		}
+	}
+
+	old := valid
+	old.TahunRilis = 1800
+	if fields := fieldsOf(t, Struct(old)); fields["tahun_rilis"] != "min" {
+		t.Fatalf("expected tahun_rilis min error, got %v", fields)
+	}
+}
+
+func TestUpdateMovieRequestRules(t *testing.T) {
+	if err := Struct(models.UpdateMovieRequest{}); err != nil {
+		t.Fatalf("expected empty update to be valid, got %v", err)
+	}
+
+	empty := ""
+	zero := 0
+	cast := []string{" "}
+	fields := fieldsOf(t, Struct(models.UpdateMovieRequest{Judul: &empty, TahunRilis: &zero, Pemeran: &cast}))
+	if fields["judul"] != "notblank" || fields["tahun_rilis"] != "min" || fields["pemeran[0]"] != "notblank" {
+		t.Fatalf("unexpected field errors: %v", fields)
+	}
+}
+
+func TestUserRequestRules(t *testing.T) {
+	if err := Struct(models.CreateUserRequest{Username: "budi.s", Password: "rahasia123", Role: "editor"}); err != nil {
+		t.Fatalf("expected valid request, got %v", err)
+	}
+	fields := fieldsOf(t, Struct(models.CreateUserRequest{Username: "budi s", Password: "short", Role: "root"}))
+	want := map[string]string{"username": "username", "password": "min", "role": "oneof"}
+	for field, rule := range want {
+		if fields[field] != rule {
+			t.Fatalf("expected %s to fail %q, got %v", field, rule, fields)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// APIKey is a long-lived credential for machine-to-machine clients.
+// Only the SHA-256 hash of the key is stored; Prefix is the visible part
+// (gf_<prefix>_...) used to look the key up and to recognise it in listings.
+type APIKey struct {
+	ID         uuid.UUID      `json:"id" db:"id"`
+	Name       string         `json:"name" db:"name"`
+	Prefix     string         `json:"prefix" db:"prefix" example:"3f9a1c7e"`
+	KeyHash    string         `json:"-" db:"key_hash"`
+	Scopes     pq.StringArray `json:"scopes" db:"scopes" swaggertype:"array,string" example:"movies:read,movies:write"`
+	CreatedBy  string         `json:"created_by" db:"created_by"`
+	CreatedAt  time.Time      `json:"created_at" db:"created_at"`
+	ExpiresAt  *time.Time     `json:"expires_at,omitempty" db:"expires_at"`
+	LastUsedAt *time.Time     `json:"last_used_at,omitempty" db:"last_used_at"`
+	RevokedAt  *time.Time     `json:"revoked_at,omitempty" db:"revoked_at"`
+}
+
+// CreateAPIKeyRequest is the body of POST /api/api-keys (admin only).
+// Scopes adalah permission rbac yang boleh dipakai key ini; permission admin, termasuk
+// movies:purge (trash, restore, hard delete), tidak bisa diberikan.
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" validate:"required,notblank,max=100" example:"nightly-ingest"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1,dive,oneof=movies:read movies:write" example:"movies:read,movies:write"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // kosong = tidak kedaluwarsa
+}
+
+// CreateAPIKeyResponse is returned once on creation; Key is never shown again
+type CreateAPIKeyResponse struct {
+	APIKey
+	Key string `json:"key" example:"gf_3f9a1c7e_Vb6cK3..."`
+}
+
+// APIKeyListResponse is the envelope of GET /api/api-keys
+type APIKeyListResponse struct {
+	Data []APIKey `json:"data"`
+}
@@ -21,35 +21,106 @@ type Movie struct {
 	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
 	CreatedBy *string    `json:"created_by,omitempty" db:"created_by"`
 	UpdatedBy *string    `json:"updated_by,omitempty" db:"updated_by"`
+	DeletedBy *string    `json:"deleted_by,omitempty" db:"deleted_by"`
 	Version   int        `json:"version" db:"version"`
 }
 
+// StripAudit clears the *_by audit fields, which name internal users and are
+// not shown to anonymous callers of the public catalogue
+func (m *Movie) StripAudit() {
+	m.CreatedBy = nil
+	m.UpdatedBy = nil
+	m.DeletedBy = nil
+}
+
 // CreateMovieRequest represents the request data for creating a new movie
 // Kolom audit diisi otomatis di backend, user hanya input data utama
 // Pemeran tetap array of string agar mudah di-parse dari JSON
 // ID, CreatedAt, UpdatedAt, dsb di-generate backend
 // CreatedBy bisa diisi dari JWT username jika perlu
 // Version diisi default 1
+// Batas panjang mengikuti VARCHAR(255)/VARCHAR(100) di database/migrations, maxyear=5 mengizinkan film yang sudah diumumkan
 // DeletedAt tidak diinput user
 type CreateMovieRequest struct {
-	Judul      string   `json:"judul" validate:"required"`
-	Genre      string   `json:"genre" validate:"required"`
-	TahunRilis int      `json:"tahun_rilis" validate:"required,min=1888"`
-	Sutradara  string   `json:"sutradara" validate:"required"`
-	Pemeran    []string `json:"pemeran" validate:"required"`
+	Judul      string   `json:"judul" validate:"required,notblank,max=255"`
+	Genre      string   `json:"genre" validate:"required,notblank,max=100"`
+	TahunRilis int      `json:"tahun_rilis" validate:"required,min=1888,maxyear=5"`
+	Sutradara  string   `json:"sutradara" validate:"required,notblank,max=100"`
+	Pemeran    []string `json:"pemeran" validate:"required,dive,notblank"`
 	CreatedBy  *string  `json:"created_by,omitempty"` // opsional, bisa diisi dari JWT
 }
 
 // UpdateMovieRequest represents the request data for updating a movie
 // Hanya field yang boleh diubah user
+// Field nil tidak divalidasi (tidak diubah), field yang dikirim divalidasi dengan aturan yang sama seperti create
 // UpdatedBy bisa diisi dari JWT username jika perlu
 // Version bisa diincrement di backend
+// Version di body (opsional) adalah versi yang diharapkan, alternatif dari header If-Match
 // DeletedAt tidak diinput user
 type UpdateMovieRequest struct {
-	Judul      *string   `json:"judul,omitempty"`
-	Genre      *string   `json:"genre,omitempty"`
-	TahunRilis *int      `json:"tahun_rilis,omitempty"`
-	Sutradara  *string   `json:"sutradara,omitempty"`
-	Pemeran    *[]string `json:"pemeran,omitempty"`
-	UpdatedBy  *string   `json:"updated_by,omitempty"` // opsional, bisa diisi dari JWT
+	Judul      *string   `json:"judul,omitempty" validate:"omitnil,notblank,max=255"`
+	Genre      *string   `json:"genre,omitempty" validate:"omitnil,notblank,max=100"`
+	TahunRilis *int      `json:"tahun_rilis,omitempty" validate:"omitnil,min=1888,maxyear=5"`
+	Sutradara  *string   `json:"sutradara,omitempty" validate:"omitnil,notblank,max=100"`
+	Pemeran    *[]string `json:"pemeran,omitempty" validate:"omitnil,dive,notblank"`
+	UpdatedBy  *string   `json:"updated_by,omitempty"`                       // opsional, bisa diisi dari JWT
+	Version    *int      `json:"version,omitempty" validate:"omitnil,min=1"` // opsional, expected version untuk optimistic locking
+}
+
+// MovieListParams holds the pagination, filter and sort options for listing movies
+// Semua filter bersifat opsional, nilai kosong berarti tidak difilter
+type MovieListParams struct {
+	Page          int
+	Limit         int
+	Genre         string
+	TahunRilisMin *int
+	TahunRilisMax *int
+	Sutradara     string
+	Pemeran       string
+	Sort          string
+	Order         string
+
+	// CursorMode switches to keyset pagination; After is the decoded position to seek past
+	CursorMode bool
+	Cursor     string
+	After      *MovieCursor
+}
+
+// MovieCursor is a keyset position in the (created_at DESC, id DESC) ordering
+type MovieCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// PageLinks holds the relative URLs of the neighbouring pages
+type PageLinks struct {
+	Next *string `json:"next,omitempty"`
+	Prev *string `json:"prev,omitempty"`
+}
+
+// MovieListResponse is the paginated envelope returned by GET /api/movies
+// Page hanya diisi pada offset pagination, NextCursor hanya pada cursor pagination
+type MovieListResponse struct {
+	Data       []Movie   `json:"data"`
+	Total      int       `json:"total"`
+	Page       int       `json:"page,omitempty"`
+	Limit      int       `json:"limit"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+	Links      PageLinks `json:"links"`
+}
+
+// MovieSearchResult is a movie matched by the search endpoint with its relevance score
+// Snippet berisi potongan teks yang sudah di-escape HTML, dengan kata yang cocok dibungkus <mark>...</mark>
+type MovieSearchResult struct {
+	Movie
+	Rank    float64 `json:"rank" db:"rank"`
+	Snippet string  `json:"snippet" db:"snippet"`
+}
+
+// MovieSearchResponse is the envelope returned by GET /api/movies/search
+// Fuzzy bernilai true jika hasil berasal dari fallback trigram (mis. salah ketik)
+type MovieSearchResponse struct {
+	Data  []MovieSearchResult `json:"data"`
+	Query string              `json:"query"`
+	Fuzzy bool                `json:"fuzzy"`
 }
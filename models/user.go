@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// User represents an account in the users table.
+// Role is one of the rbac roles: viewer, editor or admin.
+// PasswordHash is never serialized; only bcrypt hashes are stored.
+// Access tokens issued before TokensValidAfter are refused. It is set on creation, so tokens
+// of a deleted account with the same username stay invalid, and whenever the password or role changes.
+type User struct {
+	ID               uuid.UUID  `json:"id" db:"id"`
+	Username         string     `json:"username" db:"username"`
+	PasswordHash     string     `json:"-" db:"password_hash"`
+	Role             string     `json:"role" db:"role"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at" db:"updated_at"`
+	TokensValidAfter *time.Time `json:"-" db:"tokens_valid_after"`
+}
+
+// RegisterRequest is the body of POST /api/register.
+// Akun hasil registrasi selalu mendapat role viewer (hanya baca).
+type RegisterRequest struct {
+	Username string `json:"username" validate:"required,min=3,max=50,username"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
+// ChangePasswordRequest is the body of PUT /api/me/password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=8,max=72"`
+}
+
+// CreateUserRequest is the body of POST /api/users (admin only)
+type CreateUserRequest struct {
+	Username string `json:"username" validate:"required,min=3,max=50,username"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+	Role     string `json:"role" validate:"omitempty,oneof=viewer editor admin" example:"editor"`
+}
+
+// UpdateUserRequest is the body of PUT /api/users/{id} (admin only).
+// Field yang nil tidak diubah; Password mereset password tanpa perlu password lama.
+type UpdateUserRequest struct {
+	Role     *string `json:"role,omitempty" validate:"omitnil,oneof=viewer editor admin"`
+	Password *string `json:"password,omitempty" validate:"omitnil,min=8,max=72"`
+}
+
+// UserListResponse is the envelope of GET /api/users
+type UserListResponse struct {
+	Data  []User `json:"data"`
+	Total int    `json:"total"`
+	Page  int    `json:"page"`
+	Limit int    `json:"limit"`
+}